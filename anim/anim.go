@@ -0,0 +1,128 @@
+// Package anim holds animations as sequences of fully composited frames,
+// ready to be shown without further decoding.
+package anim
+
+import (
+	"image"
+	"image/draw"
+	"time"
+
+	"github.com/ktt-ol/go-insta/fit"
+	"github.com/ktt-ol/go-insta/resample"
+)
+
+// DefaultDelay is used for frames without a delay.
+const DefaultDelay = 100 * time.Millisecond
+
+// showDelays returns how long each frame of a gif or animated png is
+// shown. Frames without delay are only steps to build up the next frame
+// and get 0, so that they are merged into it, unless all frames lack a
+// delay. Those are played with DefaultDelay, like browsers do.
+func showDelays(delays []time.Duration) []time.Duration {
+	merge := false
+	for _, d := range delays {
+		if d > 0 {
+			merge = true
+		}
+	}
+	show := make([]time.Duration, len(delays))
+	for i, d := range delays {
+		switch {
+		case d > 0:
+			show[i] = d
+		case !merge || i == len(delays)-1:
+			show[i] = DefaultDelay
+		}
+	}
+	return show
+}
+
+// Frame is a complete image of the animation and the time it is shown.
+type Frame struct {
+	Image *image.RGBA
+	Delay time.Duration
+}
+
+// Clip is an animation. Loops is the number of times the frames are
+// played, 0 repeats them forever.
+type Clip struct {
+	Frames []Frame
+	Loops  int
+}
+
+// Duration returns the time of a single pass through all frames.
+func (c *Clip) Duration() time.Duration {
+	var d time.Duration
+	for _, f := range c.Frames {
+		d += f.Delay
+	}
+	return d
+}
+
+// FrameAt returns the index of the frame shown at el since the start of
+// the clip and the time left till the next frame. After the last loop,
+// the last frame is returned with a negative time left.
+func (c *Clip) FrameAt(el time.Duration) (int, time.Duration) {
+	total := c.Duration()
+	if total <= 0 || len(c.Frames) == 0 {
+		return 0, -1
+	}
+	if c.Loops > 0 && el >= total*time.Duration(c.Loops) {
+		return len(c.Frames) - 1, -1
+	}
+	el %= total
+	for i, f := range c.Frames {
+		if el < f.Delay {
+			return i, f.Delay - el
+		}
+		el -= f.Delay
+	}
+	return len(c.Frames) - 1, -1
+}
+
+// Draw draws the frame at el into dst, which makes a clip usable as Mode.
+func (c *Clip) Draw(dst draw.Image, el time.Duration) {
+	if len(c.Frames) == 0 {
+		return
+	}
+	i, _ := c.FrameAt(el)
+	f := c.Frames[i].Image
+	draw.Draw(dst, dst.Bounds(), f, f.Bounds().Min, draw.Src)
+}
+
+// Fit returns a copy of the clip with all frames fitted into w x h. An
+// automatic filter is chosen once from the first frame, so that all frames
+// are scaled alike.
+func (c *Clip) Fit(w, h int, fo fit.Options) *Clip {
+	if fo.Filter == resample.Auto && len(c.Frames) > 0 {
+		fo.Filter = resample.Choose(c.Frames[0].Image, w, h)
+	}
+	return c.convert(func(img *image.RGBA) *image.RGBA {
+		return fit.Image(img, w, h, fo)
+	})
+}
+
+// Resize returns a copy of the clip with all frames scaled to w x h, see
+// resample.Resize.
+func (c *Clip) Resize(w, h int, f resample.Filter) *Clip {
+	if f == resample.Auto && len(c.Frames) > 0 {
+		f = resample.Choose(c.Frames[0].Image, w, h)
+	}
+	return c.convert(func(img *image.RGBA) *image.RGBA {
+		return resample.Resize(img, w, h, f)
+	})
+}
+
+func (c *Clip) convert(fn func(*image.RGBA) *image.RGBA) *Clip {
+	out := &Clip{Frames: make([]Frame, len(c.Frames)), Loops: c.Loops}
+	for i, f := range c.Frames {
+		out.Frames[i] = Frame{Image: fn(f.Image), Delay: f.Delay}
+	}
+	return out
+}
+
+func clone(img *image.RGBA) *image.RGBA {
+	c := image.NewRGBA(img.Bounds())
+	draw.Draw(c, c.Bounds(), img, img.Bounds().Min, draw.Src)
+	return c
+}
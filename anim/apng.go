@@ -0,0 +1,237 @@
+package anim
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/draw"
+	"image/png"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+const pngHeader = "\x89PNG\r\n\x1a\n"
+
+// APNG dispose and blend operations of fcTL chunks.
+const (
+	apngDisposeNone       = 0
+	apngDisposeBackground = 1
+	apngDisposePrevious   = 2
+	apngBlendSource       = 0
+)
+
+type pngChunk struct {
+	typ  string
+	data []byte
+}
+
+func readChunks(data []byte) ([]pngChunk, error) {
+	if !bytes.HasPrefix(data, []byte(pngHeader)) {
+		return nil, errors.New("not a png")
+	}
+	var chunks []pngChunk
+	for p := len(pngHeader); p+8 <= len(data); {
+		n := int(binary.BigEndian.Uint32(data[p:]))
+		if n < 0 || p+12+n > len(data) {
+			return nil, errors.New("truncated png chunk")
+		}
+		c := pngChunk{typ: string(data[p+4 : p+8]), data: data[p+8 : p+8+n]}
+		chunks = append(chunks, c)
+		p += 12 + n
+		if c.typ == "IEND" {
+			break
+		}
+	}
+	return chunks, nil
+}
+
+// IsAPNG returns whether data is an animated png.
+func IsAPNG(data []byte) bool {
+	chunks, err := readChunks(data)
+	if err != nil {
+		return false
+	}
+	for _, c := range chunks {
+		switch c.typ {
+		case "acTL":
+			return true
+		case "IDAT":
+			// acTL has to be before the image data
+			return false
+		}
+	}
+	return false
+}
+
+type fcTL struct {
+	w, h, x, y     int
+	delay          time.Duration
+	dispose, blend byte
+	data           [][]byte
+}
+
+// DecodeAPNG decodes an animated png. The frames are composited on the
+// canvas of the png with their blend and dispose operations. Pngs without
+// animation are a single frame. Frames without delay are merged into the
+// next frame, like in gifs (see showDelays).
+func DecodeAPNG(r io.Reader) (*Clip, error) {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	chunks, err := readChunks(data)
+	if err != nil {
+		return nil, err
+	}
+	if len(chunks) == 0 || chunks[0].typ != "IHDR" || len(chunks[0].data) < 13 {
+		return nil, errors.New("png without IHDR")
+	}
+	ihdr := chunks[0].data
+	w, h := int(binary.BigEndian.Uint32(ihdr)), int(binary.BigEndian.Uint32(ihdr[4:]))
+
+	c := &Clip{}
+	var (
+		// shared chunks like PLTE and tRNS, needed to decode each frame
+		shared   []pngChunk
+		frames   []*fcTL
+		cur      *fcTL
+		animated bool
+		seenData bool
+	)
+	for _, ch := range chunks[1:] {
+		switch ch.typ {
+		case "acTL":
+			if len(ch.data) < 8 {
+				return nil, errors.New("invalid acTL chunk")
+			}
+			animated = true
+			c.Loops = int(binary.BigEndian.Uint32(ch.data[4:]))
+		case "fcTL":
+			if cur, err = parseFcTL(ch.data); err != nil {
+				return nil, err
+			}
+			if cur.x < 0 || cur.y < 0 || cur.x+cur.w > w || cur.y+cur.h > h {
+				return nil, errors.New("apng frame outside of the canvas")
+			}
+			frames = append(frames, cur)
+		case "IDAT":
+			seenData = true
+			if !animated && cur == nil {
+				cur = &fcTL{w: w, h: h}
+				frames = append(frames, cur)
+			}
+			// the default image is no frame without a preceding fcTL
+			if cur != nil {
+				cur.data = append(cur.data, ch.data)
+			}
+		case "fdAT":
+			if cur == nil || len(ch.data) < 4 {
+				return nil, errors.New("fdAT chunk without fcTL")
+			}
+			// skip the sequence number
+			cur.data = append(cur.data, ch.data[4:])
+		case "IEND":
+		default:
+			if !seenData {
+				shared = append(shared, ch)
+			}
+		}
+	}
+
+	var delays []time.Duration
+	for _, f := range frames {
+		if len(f.data) > 0 {
+			frames[len(delays)] = f
+			delays = append(delays, f.delay)
+		}
+	}
+	frames = frames[:len(delays)]
+	delays = showDelays(delays)
+
+	canvas := image.NewRGBA(image.Rect(0, 0, w, h))
+	for i, f := range frames {
+		img, err := decodeFrame(ihdr, shared, f)
+		if err != nil {
+			return nil, fmt.Errorf("apng frame %d: %v", i, err)
+		}
+		r := image.Rect(f.x, f.y, f.x+f.w, f.y+f.h)
+		var prev *image.RGBA
+		dispose := f.dispose
+		if dispose == apngDisposePrevious && i == 0 {
+			dispose = apngDisposeBackground
+		}
+		if dispose == apngDisposePrevious {
+			prev = clone(canvas)
+		}
+
+		op := draw.Over
+		if f.blend == apngBlendSource {
+			op = draw.Src
+		}
+		draw.Draw(canvas, r, img, img.Bounds().Min, op)
+		if delays[i] > 0 {
+			c.Frames = append(c.Frames, Frame{Image: clone(canvas), Delay: delays[i]})
+		}
+
+		switch dispose {
+		case apngDisposeBackground:
+			draw.Draw(canvas, r, image.Transparent, image.ZP, draw.Src)
+		case apngDisposePrevious:
+			canvas = prev
+		}
+	}
+	if len(c.Frames) == 0 {
+		return nil, errors.New("png without frames")
+	}
+	return c, nil
+}
+
+func parseFcTL(b []byte) (*fcTL, error) {
+	if len(b) < 26 {
+		return nil, errors.New("invalid fcTL chunk")
+	}
+	u32 := func(o int) int { return int(binary.BigEndian.Uint32(b[o:])) }
+	f := &fcTL{w: u32(4), h: u32(8), x: u32(12), y: u32(16), dispose: b[24], blend: b[25]}
+	num, den := binary.BigEndian.Uint16(b[20:]), binary.BigEndian.Uint16(b[22:])
+	if den == 0 {
+		den = 100
+	}
+	f.delay = time.Duration(num) * time.Second / time.Duration(den)
+	return f, nil
+}
+
+// decodeFrame builds a png of the frame with the header of the animation
+// and decodes it.
+func decodeFrame(ihdr []byte, shared []pngChunk, f *fcTL) (image.Image, error) {
+	var buf bytes.Buffer
+	buf.WriteString(pngHeader)
+	hdr := append([]byte(nil), ihdr...)
+	binary.BigEndian.PutUint32(hdr, uint32(f.w))
+	binary.BigEndian.PutUint32(hdr[4:], uint32(f.h))
+	writeChunk(&buf, "IHDR", hdr)
+	for _, c := range shared {
+		writeChunk(&buf, c.typ, c.data)
+	}
+	for _, d := range f.data {
+		writeChunk(&buf, "IDAT", d)
+	}
+	writeChunk(&buf, "IEND", nil)
+	return png.Decode(&buf)
+}
+
+func writeChunk(w *bytes.Buffer, typ string, data []byte) {
+	var n [4]byte
+	binary.BigEndian.PutUint32(n[:], uint32(len(data)))
+	w.Write(n[:])
+	crc := crc32.NewIEEE()
+	crc.Write([]byte(typ))
+	crc.Write(data)
+	w.WriteString(typ)
+	w.Write(data)
+	binary.BigEndian.PutUint32(n[:], crc.Sum32())
+	w.Write(n[:])
+}
@@ -0,0 +1,67 @@
+package anim
+
+import (
+	"image"
+	"image/draw"
+	"image/gif"
+	"io"
+	"time"
+)
+
+// DecodeGIF decodes all frames of a gif, see FromGIF.
+func DecodeGIF(r io.Reader) (*Clip, error) {
+	g, err := gif.DecodeAll(r)
+	if err != nil {
+		return nil, err
+	}
+	return FromGIF(g), nil
+}
+
+// FromGIF composites the frames of g on the logical screen of the gif.
+// Frames are drawn at their offset and disposed as requested: kept,
+// cleared to transparent or restored to the previous canvas.
+//
+// Frames without delay are merged into the next frame, see showDelays.
+func FromGIF(g *gif.GIF) *Clip {
+	c := &Clip{}
+	switch {
+	case g.LoopCount < 0:
+		c.Loops = 1
+	case g.LoopCount > 0:
+		c.Loops = g.LoopCount + 1
+	}
+
+	delays := make([]time.Duration, len(g.Image))
+	for i := range delays {
+		if i < len(g.Delay) {
+			delays[i] = time.Duration(g.Delay[i]) * 10 * time.Millisecond
+		}
+	}
+	delays = showDelays(delays)
+
+	canvas := image.NewRGBA(image.Rect(0, 0, g.Config.Width, g.Config.Height))
+	for i, src := range g.Image {
+		var disposal byte
+		if i < len(g.Disposal) {
+			disposal = g.Disposal[i]
+		}
+		var prev *image.RGBA
+		if disposal == gif.DisposalPrevious {
+			prev = clone(canvas)
+		}
+
+		draw.Draw(canvas, src.Bounds(), src, src.Bounds().Min, draw.Over)
+
+		if delays[i] > 0 {
+			c.Frames = append(c.Frames, Frame{Image: clone(canvas), Delay: delays[i]})
+		}
+
+		switch disposal {
+		case gif.DisposalBackground:
+			draw.Draw(canvas, src.Bounds(), image.Transparent, image.ZP, draw.Src)
+		case gif.DisposalPrevious:
+			canvas = prev
+		}
+	}
+	return c
+}
@@ -11,6 +11,7 @@ import (
 	"time"
 
 	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/theme"
 )
 
 type LevelGraph struct {
@@ -68,11 +69,11 @@ func (g *LevelGraph) Next() {
 func (g *LevelGraph) UpdateScreen(s *insta.Screen) {
 	g.mu.Lock()
 	defer g.mu.Unlock()
+	pal := theme.Current().Cycled()
 	for x := 0; x < insta.ScreenWidth; x++ {
 		for y := 0; y < insta.ScreenHeight; y++ {
 			if ((g.hist[x] + 1) / 31.0 * insta.ScreenHeight) > (insta.ScreenHeight - float64(y)) {
-				r, g, b := insta.HsvToRgb(float64(y*3), 0.7, 0.8)
-				s.Set(x, y, color.RGBA{uint8(r * 255), uint8(g * 255), uint8(b * 255), 128})
+				s.Set(x, y, pal.Hue(float64(y*3)))
 			} else {
 				s.Set(x, y, color.RGBA{0, 0, 0, 128})
 			}
@@ -0,0 +1,256 @@
+// Package board implements a moderated message queue. Messages are
+// submitted via TCP, HTTP or stdin and shown as scrolling text between the
+// regular modes.
+package board
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+const MaxLength = 200
+
+var (
+	ErrEmpty       = errors.New("empty message")
+	ErrTooLong     = errors.New("message too long")
+	ErrRateLimited = errors.New("too many messages, try again later")
+	ErrBlocked     = errors.New("message contains blocked words")
+	ErrNotFound    = errors.New("message not found")
+)
+
+type Message struct {
+	ID       int64     `json:"id"`
+	Text     string    `json:"text"`
+	Source   string    `json:"source"`
+	Created  time.Time `json:"created"`
+	Approved bool      `json:"approved"`
+}
+
+// Board is a persistent queue of messages. It is safe for concurrent use.
+type Board struct {
+	// RateLimit is the minimal interval between two messages of the same
+	// source.
+	RateLimit time.Duration
+	// Moderate requires messages to be approved before they are shown.
+	Moderate bool
+	// Blocklist contains lowercase words that are rejected.
+	Blocklist []string
+
+	mu       sync.Mutex
+	fname    string
+	state    state
+	lastSeen map[string]time.Time
+	// pruned is when expired entries were last removed from lastSeen
+	pruned time.Time
+}
+
+type state struct {
+	NextID   int64     `json:"next_id"`
+	Messages []Message `json:"messages"`
+}
+
+// NewBoard returns a board that stores its queue in fname. Queued messages
+// from a previous run are loaded.
+func NewBoard(fname string) (*Board, error) {
+	b := &Board{
+		RateLimit: time.Minute,
+		fname:     fname,
+		state:     state{NextID: 1},
+		lastSeen:  make(map[string]time.Time),
+	}
+	data, err := ioutil.ReadFile(fname)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &b.state); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// LoadBlocklist reads one blocked word per line. Empty lines and lines
+// starting with # are ignored.
+func LoadBlocklist(fname string) ([]string, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var words []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		w := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if w == "" || strings.HasPrefix(w, "#") {
+			continue
+		}
+		words = append(words, w)
+	}
+	return words, scanner.Err()
+}
+
+// Submit adds a message from source (e.g. the IP address of the sender)
+// to the queue.
+func (b *Board) Submit(source, text string) (Message, error) {
+	return b.submit(source, text, false)
+}
+
+// expire forgets senders that are no longer rate limited, at most once per
+// RateLimit.
+func (b *Board) expire(now time.Time) {
+	if now.Sub(b.pruned) < b.RateLimit {
+		return
+	}
+	for source, last := range b.lastSeen {
+		if now.Sub(last) >= b.RateLimit {
+			delete(b.lastSeen, source)
+		}
+	}
+	b.pruned = now
+}
+
+// submit adds the message. trusted messages skip rate limiting and
+// moderation.
+func (b *Board) submit(source, text string, trusted bool) (Message, error) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return Message{}, ErrEmpty
+	}
+	if len([]rune(text)) > MaxLength {
+		return Message{}, ErrTooLong
+	}
+	if b.blocked(text) {
+		return Message{}, ErrBlocked
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := time.Now()
+	if !trusted {
+		b.expire(now)
+		if last, ok := b.lastSeen[source]; ok && now.Sub(last) < b.RateLimit {
+			return Message{}, ErrRateLimited
+		}
+		b.lastSeen[source] = now
+	}
+
+	m := Message{
+		ID:       b.state.NextID,
+		Text:     text,
+		Source:   source,
+		Created:  now,
+		Approved: trusted || !b.Moderate,
+	}
+	b.state.NextID++
+	b.state.Messages = append(b.state.Messages, m)
+	return m, b.save()
+}
+
+func (b *Board) blocked(text string) bool {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, w := range words {
+		for _, bw := range b.Blocklist {
+			if w == bw {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Messages returns all queued messages, including messages that are not
+// approved.
+func (b *Board) Messages() []Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]Message(nil), b.state.Messages...)
+}
+
+// Approve allows the message to be shown.
+func (b *Board) Approve(id int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.state.Messages {
+		if b.state.Messages[i].ID == id {
+			b.state.Messages[i].Approved = true
+			return b.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// Reject removes the message from the queue.
+func (b *Board) Reject(id int64) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, m := range b.state.Messages {
+		if m.ID == id {
+			b.state.Messages = append(b.state.Messages[:i], b.state.Messages[i+1:]...)
+			return b.save()
+		}
+	}
+	return ErrNotFound
+}
+
+// Next removes and returns the oldest approved message.
+func (b *Board) Next() (Message, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i, m := range b.state.Messages {
+		if m.Approved {
+			b.state.Messages = append(b.state.Messages[:i], b.state.Messages[i+1:]...)
+			if err := b.save(); err != nil {
+				log.Printf("error: saving message board: %v", err)
+			}
+			return m, true
+		}
+	}
+	return Message{}, false
+}
+
+// save writes the queue to disk. b.mu needs to be locked.
+func (b *Board) save() error {
+	data, err := json.MarshalIndent(b.state, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(b.fname), ".board")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), b.fname)
+}
+
+// ReadLines submits each line of r as a trusted message, e.g. for stdin.
+func (b *Board) ReadLines(r io.Reader, source string) error {
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		if _, err := b.submit(source, scanner.Text(), true); err != nil && err != ErrEmpty {
+			log.Printf("skipping message from %s: %v", source, err)
+		}
+	}
+	return scanner.Err()
+}
@@ -0,0 +1,133 @@
+package board
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// ListenTCP accepts messages on addr, one message per line. Each line is
+// answered with "ok" or the error.
+func (b *Board) ListenTCP(addr string) error {
+	l, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer l.Close()
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func(c net.Conn) {
+			defer c.Close()
+			source := hostOf(c.RemoteAddr().String())
+			scanner := bufio.NewScanner(c)
+			for scanner.Scan() {
+				if _, err := b.Submit(source, scanner.Text()); err != nil {
+					fmt.Fprintln(c, err)
+				} else {
+					fmt.Fprintln(c, "ok")
+				}
+			}
+		}(conn)
+	}
+}
+
+func hostOf(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// Handler returns an HTTP handler for the board:
+//
+//	POST /messages                 submit form value "text"
+//	GET  /messages                 list queued messages
+//	POST /messages/<id>/approve    approve message
+//	POST /messages/<id>/reject     remove message
+//
+// Listing and moderation require the token (as form value "token") if
+// token is not empty.
+func (b *Board) Handler(token string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(r.URL.Path, "/")
+		parts := strings.Split(path, "/")
+		if parts[0] != "messages" || len(parts) > 3 || len(parts) == 2 {
+			http.NotFound(w, r)
+			return
+		}
+
+		if len(parts) == 1 && r.Method == http.MethodPost {
+			m, err := b.Submit(hostOf(r.RemoteAddr), r.FormValue("text"))
+			switch err {
+			case nil:
+				writeJSON(w, http.StatusAccepted, m)
+			case ErrRateLimited:
+				http.Error(w, err.Error(), http.StatusTooManyRequests)
+			case ErrBlocked:
+				http.Error(w, err.Error(), http.StatusForbidden)
+			case ErrEmpty, ErrTooLong:
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				log.Printf("error: submitting message: %v", err)
+				http.Error(w, "internal error", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		if token != "" && r.FormValue("token") != token {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+
+		if len(parts) == 1 {
+			if r.Method != http.MethodGet {
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			writeJSON(w, http.StatusOK, b.Messages())
+			return
+		}
+
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		id, err := strconv.ParseInt(parts[1], 10, 64)
+		if err != nil {
+			http.NotFound(w, r)
+			return
+		}
+		switch parts[2] {
+		case "approve":
+			err = b.Approve(id)
+		case "reject":
+			err = b.Reject(id)
+		default:
+			http.NotFound(w, r)
+			return
+		}
+		if err == ErrNotFound {
+			http.NotFound(w, r)
+		} else if err != nil {
+			log.Printf("error: moderating message %d: %v", id, err)
+			http.Error(w, "internal error", http.StatusInternalServerError)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Printf("error: writing response: %v", err)
+	}
+}
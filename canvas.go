@@ -0,0 +1,207 @@
+package insta
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"time"
+)
+
+// Canvas is a virtual image of any size. A Camera shows a part of it on the
+// screen.
+type Canvas struct {
+	*image.RGBA
+}
+
+// NewCanvas returns a black canvas with the size w x h.
+func NewCanvas(w, h int) *Canvas {
+	c := &Canvas{image.NewRGBA(image.Rect(0, 0, w, h))}
+	draw.Draw(c.RGBA, c.Bounds(), image.Black, image.ZP, draw.Src)
+	return c
+}
+
+// NewCanvasFromImage returns a canvas with a copy of img.
+func NewCanvasFromImage(img image.Image) *Canvas {
+	b := img.Bounds()
+	c := NewCanvas(b.Dx(), b.Dy())
+	draw.Draw(c.RGBA, c.Bounds(), img, b.Min, draw.Over)
+	return c
+}
+
+// Camera is a viewport into a canvas. X/Y is the canvas position shown in
+// the center of the viewport. The position is updated with Update, either by
+// a constant velocity, by an eased pan to a target or by following a point.
+type Camera struct {
+	X, Y float64
+	// Zoom scales each canvas pixel to Zoom x Zoom screen pixels.
+	Zoom int
+	// Wrap repeats the canvas in all directions, otherwise the camera is
+	// kept inside of the canvas.
+	Wrap bool
+	// VX, VY is the velocity in canvas pixels per second.
+	VX, VY float64
+
+	pan    *pan
+	follow func() (x, y float64)
+	// lag is the time after which the camera covered ~63% of the distance
+	// to the followed point.
+	lag  time.Duration
+	last time.Duration
+}
+
+type pan struct {
+	fromX, fromY, toX, toY float64
+	start, d               time.Duration
+	started                bool
+}
+
+// NewCamera returns a camera centered at x/y without zoom.
+func NewCamera(x, y float64) *Camera {
+	return &Camera{X: x, Y: y, Zoom: 1}
+}
+
+// PanTo moves the camera to x/y in d. The movement eases in and out and
+// starts with the next Update. It stops following and moving.
+func (cam *Camera) PanTo(x, y float64, d time.Duration) {
+	cam.pan = &pan{toX: x, toY: y, d: d}
+	cam.follow = nil
+	cam.VX, cam.VY = 0, 0
+}
+
+// Panning returns whether a PanTo is not finished yet.
+func (cam *Camera) Panning() bool {
+	return cam.pan != nil
+}
+
+// Follow moves the camera smoothly towards the point returned by fn on each
+// Update. Smaller lags follow the point closer, 0 centers it immediately.
+func (cam *Camera) Follow(fn func() (x, y float64), lag time.Duration) {
+	cam.follow = fn
+	cam.lag = lag
+	cam.pan = nil
+}
+
+// Update moves the camera for the time el since the start of the animation.
+func (cam *Camera) Update(el time.Duration) {
+	dt := (el - cam.last).Seconds()
+	cam.last = el
+	if dt < 0 {
+		dt = 0
+	}
+
+	switch {
+	case cam.pan != nil:
+		p := cam.pan
+		if !p.started {
+			p.fromX, p.fromY, p.start, p.started = cam.X, cam.Y, el, true
+		}
+		t := 1.0
+		if p.d > 0 {
+			t = float64(el-p.start) / float64(p.d)
+		}
+		if t >= 1 {
+			cam.X, cam.Y = p.toX, p.toY
+			cam.pan = nil
+			break
+		}
+		t = t * t * (3 - 2*t)
+		cam.X = p.fromX + (p.toX-p.fromX)*t
+		cam.Y = p.fromY + (p.toY-p.fromY)*t
+	case cam.follow != nil:
+		x, y := cam.follow()
+		a := 1.0
+		if cam.lag > 0 {
+			a = 1 - math.Exp(-dt/cam.lag.Seconds())
+		}
+		cam.X += (x - cam.X) * a
+		cam.Y += (y - cam.Y) * a
+	default:
+		cam.X += cam.VX * dt
+		cam.Y += cam.VY * dt
+	}
+}
+
+// Render draws the part of src that is visible by the camera into dst.
+func (cam *Camera) Render(dst draw.Image, src image.Image) {
+	db, sb := dst.Bounds(), src.Bounds()
+	zoom := cam.Zoom
+	if zoom < 1 {
+		zoom = 1
+	}
+	sw, sh := sb.Dx(), sb.Dy()
+	if sw == 0 || sh == 0 {
+		return
+	}
+	// visible size in canvas pixels
+	vw, vh := float64(db.Dx())/float64(zoom), float64(db.Dy())/float64(zoom)
+	cx, cy := cam.X, cam.Y
+	if !cam.Wrap {
+		cx = clampCenter(cx, vw, sw)
+		cy = clampCenter(cy, vh, sh)
+		cam.X, cam.Y = cx, cy
+	}
+	x0 := int(math.Floor(cx - vw/2))
+	y0 := int(math.Floor(cy - vh/2))
+
+	for y := 0; y < db.Dy(); y++ {
+		sy := y0 + y/zoom
+		if cam.Wrap {
+			sy = mod(sy, sh)
+		}
+		for x := 0; x < db.Dx(); x++ {
+			sx := x0 + x/zoom
+			if cam.Wrap {
+				sx = mod(sx, sw)
+			}
+			var c color.Color = color.Black
+			if sx >= 0 && sy >= 0 && sx < sw && sy < sh {
+				c = src.At(sb.Min.X+sx, sb.Min.Y+sy)
+			}
+			dst.Set(db.Min.X+x, db.Min.Y+y, c)
+		}
+	}
+}
+
+// clampCenter keeps a view of size v centered at c inside of [0, size]. Views
+// larger than size are centered.
+func clampCenter(c, v float64, size int) float64 {
+	if v >= float64(size) {
+		return float64(size) / 2
+	}
+	return math.Max(v/2, math.Min(float64(size)-v/2, c))
+}
+
+func mod(a, b int) int {
+	a %= b
+	if a < 0 {
+		a += b
+	}
+	return a
+}
+
+// Viewport is a Mode that draws Scene into a Canvas and shows it through
+// the Camera. Scene is optional, e.g. for static panoramas.
+type Viewport struct {
+	Canvas *Canvas
+	Camera *Camera
+	Scene  Mode
+}
+
+// NewViewport returns a viewport for a canvas with the size w x h, with the
+// camera at the center.
+func NewViewport(w, h int, scene Mode) *Viewport {
+	return &Viewport{
+		Canvas: NewCanvas(w, h),
+		Camera: NewCamera(float64(w)/2, float64(h)/2),
+		Scene:  scene,
+	}
+}
+
+func (v *Viewport) Draw(dst draw.Image, el time.Duration) {
+	if v.Scene != nil {
+		v.Scene.Draw(v.Canvas, el)
+	}
+	v.Camera.Update(el)
+	v.Camera.Render(dst, v.Canvas)
+}
@@ -0,0 +1,385 @@
+// Package clock renders the current time in several clock faces.
+package clock
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"time"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/gfx"
+	"github.com/ktt-ol/go-insta/theme"
+	"github.com/ktt-ol/go-insta/timing"
+)
+
+type Face int
+
+const (
+	Digital Face = iota
+	Analog
+	Binary
+	Words
+)
+
+var faceNames = []string{"digital", "analog", "binary", "words"}
+
+func (f Face) String() string {
+	if int(f) < len(faceNames) {
+		return faceNames[f]
+	}
+	return fmt.Sprintf("Face(%d)", int(f))
+}
+
+// ParseFace returns the face for the name as returned by Face.String.
+func ParseFace(s string) (Face, error) {
+	for i, n := range faceNames {
+		if n == s {
+			return Face(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown clock face %q", s)
+}
+
+type Clock struct {
+	Face Face
+	// Seconds enables an animation for the seconds.
+	Seconds bool
+	// Date adds a line with weekday and date.
+	Date bool
+
+	Color  color.RGBA
+	Accent color.RGBA
+	Dim    color.RGBA
+}
+
+func NewClock(face Face, seconds, date bool) *Clock {
+	th := theme.Current()
+	return &Clock{
+		Face:    face,
+		Seconds: seconds,
+		Date:    date,
+		Color:   th.Foreground,
+		Accent:  th.Accent,
+		Dim:     th.Dim,
+	}
+}
+
+// Draw paints the clock for the current time, to use the clock as
+// insta.Mode.
+func (c *Clock) Draw(dst draw.Image, el time.Duration) {
+	c.Paint(dst, timing.Now())
+}
+
+// Paint paints the clock for time t. The faces adapt to the size of dst.
+func (c *Clock) Paint(dst draw.Image, t time.Time) {
+	gfx.Fill(dst, color.Black)
+	switch c.Face {
+	case Analog:
+		c.paintAnalog(dst, t)
+	case Binary:
+		c.paintBinary(dst, t)
+	case Words:
+		c.paintWords(dst, t)
+	default:
+		c.paintDigital(dst, t)
+	}
+}
+
+// dateLine returns the longest date line that fits into width w.
+func dateLine(t time.Time, w int) string {
+	for _, l := range []string{
+		fmt.Sprintf("%s %02d.%02d.", t.Weekday().String()[:3], t.Day(), int(t.Month())),
+		fmt.Sprintf("%02d.%02d", t.Day(), int(t.Month())),
+		fmt.Sprintf("%d", t.Day()),
+	} {
+		if textWidth(l) <= w {
+			return l
+		}
+	}
+	return ""
+}
+
+func textWidth(text string) int {
+	return font.MeasureString(insta.Face3x5, text).Ceil() - 1
+}
+
+func drawText(dst draw.Image, text string, x, y int, col color.Color) {
+	d := &font.Drawer{
+		Dst:  dst,
+		Src:  image.NewUniform(col),
+		Face: insta.Face3x5,
+		Dot:  fixed.P(x, y+insta.Face3x5.Ascent),
+	}
+	d.DrawString(text)
+}
+
+// drawTextCentered draws text with the top at y.
+func drawTextCentered(dst draw.Image, text string, y int, col color.Color) {
+	drawText(dst, text, (dst.Bounds().Dx()-textWidth(text))/2, y, col)
+}
+
+// segments of a seven-segment display: a, b, c, d, e, f, g
+var digitSegments = [10]uint8{
+	0x3f, 0x06, 0x5b, 0x4f, 0x66, 0x6d, 0x7d, 0x07, 0x7f, 0x6f,
+}
+
+// drawDigit draws a seven-segment digit into r.
+func drawDigit(dst draw.Image, d int, r image.Rectangle, col color.Color) {
+	w, h := r.Dx(), r.Dy()
+	st := w / 5
+	if st < 1 {
+		st = 1
+	}
+	m := h/2 - st/2
+	segs := [7]image.Rectangle{
+		image.Rect(0, 0, w, st),      // a
+		image.Rect(w-st, 0, w, m+st), // b
+		image.Rect(w-st, m, w, h),    // c
+		image.Rect(0, h-st, w, h),    // d
+		image.Rect(0, m, st, h),      // e
+		image.Rect(0, 0, st, m+st),   // f
+		image.Rect(0, m, w, m+st),    // g
+	}
+	for i, s := range segs {
+		if digitSegments[d]&(1<<uint(i)) != 0 {
+			gfx.FillRect(dst, s.Add(r.Min), col)
+		}
+	}
+}
+
+// drawTime draws two numbers with two digits each, centered into r. The
+// numbers are separated by a colon if r is wide enough, otherwise they are
+// stacked.
+func drawTime(dst draw.Image, a, b int, r image.Rectangle, col, colon color.Color) {
+	w, h := r.Dx(), r.Dy()
+
+	// digit width side by side and stacked
+	dwSide := (w - 12) / 4
+	if dwSide > h/2 {
+		dwSide = h / 2
+	}
+	dwStacked := (w - 4) / 2
+	if dwStacked > (h-2)/4 {
+		dwStacked = (h - 2) / 4
+	}
+
+	if dwSide >= dwStacked {
+		dw, dh := dwSide, 2*dwSide
+		x := r.Min.X + (w-4*dw-10)/2
+		y := r.Min.Y + (h-dh)/2
+		digit := func(d, x int) {
+			drawDigit(dst, d, image.Rect(x, y, x+dw, y+dh), col)
+		}
+		digit(a/10, x)
+		digit(a%10, x+dw+2)
+		digit(b/10, x+2*dw+8)
+		digit(b%10, x+3*dw+10)
+		cx := x + 2*dw + 4
+		dot := dw / 5
+		if dot < 1 {
+			dot = 1
+		}
+		gfx.FillRect(dst, image.Rect(cx, y+dh/4, cx+dot, y+dh/4+dot), colon)
+		gfx.FillRect(dst, image.Rect(cx, y+dh*3/4-dot, cx+dot, y+dh*3/4), colon)
+		return
+	}
+
+	dw, dh := dwStacked, 2*dwStacked
+	x := r.Min.X + (w-2*dw-2)/2
+	y := r.Min.Y + (h-2*dh-2)/2
+	for i, n := range []int{a, b} {
+		yy := y + i*(dh+2)
+		drawDigit(dst, n/10, image.Rect(x, yy, x+dw, yy+dh), col)
+		drawDigit(dst, n%10, image.Rect(x+dw+2, yy, x+2*dw+2, yy+dh), col)
+	}
+}
+
+func (c *Clock) paintDigital(dst draw.Image, t time.Time) {
+	r := dst.Bounds()
+	if c.Date {
+		r.Max.Y -= 7
+		drawTextCentered(dst, dateLine(t, r.Dx()), r.Max.Y+1, c.Color)
+	}
+
+	colon := c.Color
+	if c.Seconds {
+		// blink the colon and grow a bar with the seconds of the minute
+		if t.Nanosecond() >= 5e8 {
+			colon = c.Dim
+		}
+		sec := float64(t.Second()) + float64(t.Nanosecond())/1e9
+		gfx.FillRect(dst, image.Rect(0, 0, int(sec/60*float64(r.Dx())), 1), c.Accent)
+		r.Min.Y += 2
+	}
+	drawTime(dst, t.Hour(), t.Minute(), r, c.Color, colon)
+}
+
+func (c *Clock) paintAnalog(dst draw.Image, t time.Time) {
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	cx, cy := float64(w)/2-0.5, float64(h)/2-0.5
+	r := math.Min(float64(w), float64(h))/2 - 1
+
+	for i := 0; i < 12; i++ {
+		a := float64(i) / 12 * 2 * math.Pi
+		col := c.Dim
+		if i%3 == 0 {
+			col = c.Color
+		}
+		gfx.Set(dst, int(math.Round(cx+math.Sin(a)*r)), int(math.Round(cy-math.Cos(a)*r)), col)
+	}
+
+	// weekday and day left and right of the dial, if there is room
+	if c.Date && float64(w)-2*r >= 2*12 {
+		drawText(dst, t.Weekday().String()[:3], 0, 0, c.Dim)
+		drawText(dst, fmt.Sprintf("%2d", t.Day()), w-7, 0, c.Dim)
+	}
+
+	sec := float64(t.Second()) + float64(t.Nanosecond())/1e9
+	min := float64(t.Minute()) + sec/60
+	hour := float64(t.Hour()%12) + min/60
+
+	hand := func(frac, length float64, col color.RGBA) {
+		a := frac * 2 * math.Pi
+		gfx.LineAA(dst, cx, cy, cx+math.Sin(a)*length, cy-math.Cos(a)*length, col)
+	}
+	hand(hour/12, r*0.55, c.Color)
+	hand(min/60, r*0.85, c.Color)
+	if c.Seconds {
+		hand(sec/60, r*0.9, c.Accent)
+	}
+}
+
+func (c *Clock) paintBinary(dst draw.Image, t time.Time) {
+	cols := []int{t.Hour() / 10, t.Hour() % 10, t.Minute() / 10, t.Minute() % 10}
+	if c.Seconds {
+		cols = append(cols, t.Second()/10, t.Second()%10)
+	}
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	if c.Date {
+		h -= 7
+		drawTextCentered(dst, dateLine(t, w), h+1, c.Color)
+	}
+	pitch := w / len(cols)
+	if pitch > h/4 {
+		pitch = h / 4
+	}
+	size := pitch - pitch/4
+	if size < 1 {
+		size = 1
+	}
+	x0 := (w - len(cols)*pitch + pitch - size) / 2
+	y0 := (h - 4*pitch + pitch - size) / 2
+	for i, v := range cols {
+		x := x0 + i*pitch
+		for bit := 0; bit < 4; bit++ {
+			y := y0 + (3-bit)*pitch
+			col := c.Dim
+			if v&(1<<uint(bit)) != 0 {
+				col = c.Color
+				if i >= 4 {
+					col = c.Accent
+				}
+			}
+			gfx.FillRect(dst, image.Rect(x, y, x+size, y+size), col)
+		}
+	}
+}
+
+var (
+	hourWords = []string{
+		"TWELVE", "ONE", "TWO", "THREE", "FOUR", "FIVE",
+		"SIX", "SEVEN", "EIGHT", "NINE", "TEN", "ELEVEN",
+	}
+	minuteWords = []string{
+		"", "FIVE", "TEN", "QUARTER", "TWENTY", "TWENTY FIVE", "HALF",
+	}
+)
+
+// timeWords returns the time in words, rounded down to five minutes.
+func timeWords(t time.Time) []string {
+	m := t.Minute() / 5
+	h := t.Hour() % 12
+	lines := []string{"IT IS"}
+	switch {
+	case m == 0:
+		return append(lines, hourWords[h], "O'CLOCK")
+	case m <= 6:
+		lines = append(lines, minuteWords[m], "PAST")
+	default:
+		lines = append(lines, minuteWords[12-m], "TO")
+		h = (h + 1) % 12
+	}
+	return append(lines, hourWords[h])
+}
+
+// wrapWords splits lines that are wider than w at spaces.
+func wrapWords(lines []string, w int) []string {
+	var res []string
+	for _, l := range lines {
+		if textWidth(l) <= w {
+			res = append(res, l)
+			continue
+		}
+		res = append(res, strings.Fields(l)...)
+	}
+	return res
+}
+
+func (c *Clock) paintWords(dst draw.Image, t time.Time) {
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	lines := wrapWords(timeWords(t), w)
+	height := len(lines)*7 - 2
+	if c.Date {
+		height += 8
+	}
+	y := (h - height) / 2
+	for _, l := range lines {
+		drawTextCentered(dst, l, y, c.Color)
+		y += 7
+	}
+	if c.Date {
+		drawTextCentered(dst, dateLine(t, w), y+1, c.Dim)
+	}
+
+	// one dot in each corner for every minute past the five
+	corners := []image.Point{{0, 0}, {w - 1, 0}, {w - 1, h - 1}, {0, h - 1}}
+	for i := 0; i < t.Minute()%5; i++ {
+		gfx.Set(dst, corners[i].X, corners[i].Y, c.Accent)
+	}
+
+	if c.Seconds && w >= 2 && h >= 2 {
+		// a dot running around the border once a minute
+		perimeter := 2 * (w + h - 2)
+		p := (t.Second()*1000 + t.Nanosecond()/1e6) * perimeter / 60000
+		for _, pt := range []image.Point{borderPoint(p, w, h), borderPoint(p+1, w, h)} {
+			gfx.Set(dst, pt.X, pt.Y, c.Accent)
+		}
+	}
+}
+
+// borderPoint returns the point p pixels clockwise along the border of a
+// w x h rectangle.
+func borderPoint(p, w, h int) image.Point {
+	n := 2 * (w + h - 2)
+	if n <= 0 {
+		return image.Pt(0, 0)
+	}
+	p %= n
+	switch {
+	case p < w:
+		return image.Pt(p, 0)
+	case p < w+h-1:
+		return image.Pt(w-1, p-w+1)
+	case p < 2*w+h-2:
+		return image.Pt(w-1-(p-w-h+2), h-1)
+	default:
+		return image.Pt(0, h-1-(p-2*w-h+3))
+	}
+}
@@ -0,0 +1,167 @@
+package clock
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/gfx"
+	"github.com/ktt-ol/go-insta/theme"
+	"github.com/ktt-ol/go-insta/timing"
+)
+
+// Countdown shows the remaining time till End with a progress bar and
+// celebrates when the time is up.
+type Countdown struct {
+	Start, End time.Time
+	// Celebrate is the duration of the animation at zero.
+	Celebrate time.Duration
+
+	Color color.RGBA
+	// Final is used for the digits in the last minute.
+	Final color.RGBA
+	Bar   color.RGBA
+	Dim   color.RGBA
+
+	// Lock, if set, is held by Run while a frame is painted and shown, so
+	// that others can pause the countdown to use the client.
+	Lock sync.Locker
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewCountdown returns a countdown from now till end.
+func NewCountdown(end time.Time) *Countdown {
+	th := theme.Current()
+	return &Countdown{
+		Start:     timing.Now(),
+		End:       end,
+		Celebrate: 10 * time.Second,
+		Color:     th.Foreground,
+		Final:     th.Accent,
+		Bar:       th.Color(0.6),
+		Dim:       th.Dim,
+		stop:      make(chan struct{}),
+	}
+}
+
+// ParseTarget parses either a duration (e.g. "10m" or "90s") or a time of
+// day (e.g. "18:30"). A time of day that already passed refers to the next
+// day.
+func ParseTarget(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(d), nil
+	}
+	for _, layout := range []string{"15:04:05", "15:04"} {
+		t, err := time.ParseInLocation(layout, s, now.Location())
+		if err != nil {
+			continue
+		}
+		t = time.Date(now.Year(), now.Month(), now.Day(),
+			t.Hour(), t.Minute(), t.Second(), 0, now.Location())
+		if !t.After(now) {
+			t = t.AddDate(0, 0, 1)
+		}
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid countdown target %q, expected duration or HH:MM", s)
+}
+
+// Stop ends a running countdown.
+func (cd *Countdown) Stop() {
+	cd.stopOnce.Do(func() { close(cd.stop) })
+}
+
+// Run plays the countdown and the celebration till the end or till Stop is
+// called.
+func (cd *Countdown) Run(c insta.Client) {
+	s := insta.NewScreen()
+	till := cd.End.Add(cd.Celebrate)
+	for now := timing.Now(); now.Before(till); now = timing.Now() {
+		select {
+		case <-cd.stop:
+			return
+		default:
+		}
+		if cd.Lock != nil {
+			cd.Lock.Lock()
+		}
+		cd.Paint(s, timing.Now())
+		c.SetScreen(s)
+		if cd.Lock != nil {
+			cd.Lock.Unlock()
+		}
+	}
+}
+
+// Draw paints the countdown for the current time, to use the countdown as
+// insta.Mode.
+func (cd *Countdown) Draw(dst draw.Image, el time.Duration) {
+	cd.Paint(dst, timing.Now())
+}
+
+// Paint paints the countdown for time t.
+func (cd *Countdown) Paint(dst draw.Image, t time.Time) {
+	gfx.Fill(dst, color.Black)
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	digits := image.Rect(0, 0, w, h-5)
+
+	remaining := cd.End.Sub(t)
+	if remaining <= 0 {
+		cd.paintCelebration(dst, digits, -remaining)
+		return
+	}
+
+	col := cd.Color
+	if remaining <= time.Minute {
+		col = cd.Final
+	}
+
+	// round up, the countdown shows 00:01 in the last second
+	secs := int((remaining + time.Second - 1) / time.Second)
+	a, b := secs/60, secs%60
+	if secs >= 100*60 {
+		// show hours and minutes for long countdowns
+		a, b = secs/3600, secs/60%60
+	}
+	if a > 99 {
+		a = 99
+	}
+	colon := col
+	if remaining%time.Second < time.Second/2 {
+		colon = cd.Dim
+	}
+	drawTime(dst, a, b, digits, col, colon)
+
+	total := cd.End.Sub(cd.Start)
+	done := 1.0
+	if total > 0 {
+		done = 1 - float64(remaining)/float64(total)
+	}
+	bar := image.Rect(0, h-4, w, h-1)
+	gfx.FillRect(dst, bar, cd.Dim)
+	bar.Max.X = int(done * float64(w))
+	gfx.FillRect(dst, bar, cd.Bar)
+}
+
+func (cd *Countdown) paintCelebration(dst draw.Image, digits image.Rectangle, since time.Duration) {
+	// flash the background for the first seconds
+	if since < 3*time.Second && since%(250*time.Millisecond) < 125*time.Millisecond {
+		gfx.Fill(dst, cd.Final)
+	}
+	// confetti
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	pal := theme.Current().Cycled()
+	for i := 0; i < w*h/50; i++ {
+		dst.Set(rand.Intn(w), rand.Intn(h), pal.At(rand.Float64()))
+	}
+	drawTime(dst, 0, 0, digits, cd.Color, cd.Color)
+}
@@ -2,10 +2,13 @@ package main
 
 import (
 	"flag"
+	"fmt"
+	"image/draw"
 	"log"
 	"math/rand"
 	"net/http"
 	_ "net/http/pprof"
+	"os"
 	"strconv"
 	"strings"
 	"time"
@@ -15,9 +18,21 @@ import (
 	"github.com/tarm/serial"
 
 	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/anim"
 	"github.com/ktt-ol/go-insta/audio"
+	"github.com/ktt-ol/go-insta/board"
+	"github.com/ktt-ol/go-insta/clock"
+	"github.com/ktt-ol/go-insta/fit"
+	"github.com/ktt-ol/go-insta/layer"
+	"github.com/ktt-ol/go-insta/layout"
+	"github.com/ktt-ol/go-insta/ledrec"
 	"github.com/ktt-ol/go-insta/life"
+	"github.com/ktt-ol/go-insta/media"
+	"github.com/ktt-ol/go-insta/resample"
+	"github.com/ktt-ol/go-insta/scene"
 	"github.com/ktt-ol/go-insta/snake"
+	"github.com/ktt-ol/go-insta/theme"
+	"github.com/ktt-ol/go-insta/video"
 )
 
 var addrs = []string{
@@ -41,6 +56,10 @@ func init() {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "render" {
+		renderMain(os.Args[2:])
+		return
+	}
 
 	go func() {
 		log.Println(http.ListenAndServe("localhost:6060", nil))
@@ -49,25 +68,106 @@ func main() {
 	var (
 		fps            = flag.Int("fps", 25, "fps")
 		runLife        = flag.Duration("life", 0, "run life for duration")
+		lifeWidth      = flag.Int("lifewidth", insta.ScreenWidth, "width of the life field, wider fields scroll past")
+		runPan         = flag.Duration("pan", 0, "scroll -panimage for duration")
+		panImage       = flag.String("panimage", "", "panorama image for -pan")
+		panSpeed       = flag.String("panspeed", "15,0", "x,y velocity of -pan in pixels per second")
 		runSnake       = flag.Duration("snake", 0, "run snake for duration")
 		runSpaceflight = flag.Duration("spaceflight", 0, "run spaceflight for duration")
 		runLogo        = flag.Bool("logo", false, "show mainframe logo")
 		runAudio       = flag.Duration("audio", 0, "audio graph duration")
 		runRainbow     = flag.Duration("rainbow", 0, "rainbow duration")
-		runGifs        = flag.Duration("gifs", 0, "gif repeat duration")
+		runGifs        = flag.Duration("gifs", 0, "gif repeat duration, per gif of -gifcount")
+		gifCount       = flag.Int("gifcount", 1, "number of gifs per -gifs round")
+		gifTag         = flag.String("giftag", "", "only play gifs with this tag of gifs/.tags")
+		runImages      = flag.Duration("images", 0, "duration of a random image or gif of the images directory")
+		imageTag       = flag.String("imagetag", "", "only show images with this tag of images/.tags")
+		videoFile      = flag.String("video", "", "play YUV4MPEG2 or raw RGB video file, - reads from stdin")
+		videoRaw       = flag.String("videoraw", "", "size and frame rate of raw RGB -video like 54x36@25")
+		runVideo       = flag.Duration("videoduration", 0, "loop -video for duration, defaults to playing it once")
+		recFile        = flag.String("recording", "", "play Glediator or Jinx! recording")
+		recFormat      = flag.String("recformat", "16x16@25", "matrix size, frame rate and options of -recording like '16x16@30 glediator serpentine grb'")
+		runRecording   = flag.Duration("recduration", 0, "loop -recording for duration, defaults to playing it once")
+		runSlideshow   = flag.Duration("slideshow", 0, "slideshow of the images directory with pan and zoom for duration")
+		slideTime      = flag.Duration("slidetime", 8*time.Second, "time of each image of -slideshow")
+		slideFade      = flag.Duration("slidefade", time.Second, "crossfade between the images of -slideshow")
+		slideCaptions  = flag.Bool("slidecaptions", false, "show file names as captions in -slideshow")
+		mediaCache     = flag.String("mediacache", ".mediacache", "directory for pre-rendered frames of the gifs and images directories, empty disables the cache")
+		runClock       = flag.Duration("clock", 0, "clock duration")
+		clockFace      = flag.String("clockface", "digital", "clock face: digital, analog, binary or words")
+		clockSeconds   = flag.Bool("clockseconds", false, "animate seconds of clock")
+		clockDate      = flag.Bool("clockdate", false, "show date line on clock")
+		messages       = flag.String("messages", "", "queue file for message board, shows messages between modes")
+		msgTCP         = flag.String("msgtcp", ":2324", "TCP address for message board submissions, one message per line")
+		msgHTTP        = flag.String("msghttp", "", "HTTP address for message board submissions and moderation")
+		msgToken       = flag.String("msgtoken", "", "token required for message moderation via HTTP, required with -msgmoderate")
+		msgStdin       = flag.Bool("msgstdin", false, "read messages from stdin")
+		msgBlocklist   = flag.String("msgblocklist", "", "file with blocked words, one per line")
+		msgModerate    = flag.Bool("msgmoderate", false, "messages require approval")
+		msgRate        = flag.Duration("msgrate", time.Minute, "minimal interval between messages of one sender")
+		countdown      = flag.String("countdown", "", "run countdown for duration (10m) or till time of day (18:30) before other modes")
+		runPipe        = flag.Bool("pipe", false, "scroll lines read from stdin")
+		pipeSpeed      = flag.Float64("pipespeed", 25, "scroll speed of -pipe in pixels per second")
+		runLayout      = flag.Duration("layout", 0, "split screen duration")
+		layoutSpec     = flag.String("layoutspec", "clock=p0,0,1,2 life=p1,0,2,2", "regions of -layout: mode=x,y,w,h in pixels or with p prefix in panels; modes: clock, life, rainbow, recording, text")
+		themes         = flag.String("theme", "default", "color theme, a comma separated list switches the theme after each round; themes: "+strings.Join(theme.Names(), ", "))
+		themeCycle     = flag.Duration("themecycle", 0, "rotate the theme palette once in this interval")
+		sceneFile      = flag.String("scene", "", "play scene from JSON file")
+		sceneDuration  = flag.Duration("sceneduration", 0, "duration of looping -scene, defaults to the scene duration")
+		sceneCheck     = flag.Bool("scenecheck", false, "validate -scene and exit")
+		fitImages      = flag.String("fit", "stretch", "fit of images and gifs: stretch, contain, cover, center or smart, optionally followed by a background color and a filter like 'contain 202020 box'; a .fit file in the gif directory overrides it")
+		filter         = flag.String("filter", "auto", "scaling filter of images and gifs: auto, box, lanczos or nearest")
 		runServer      = flag.Bool("server", false, "start TCP server on port 2323, accepting images")
+		overlay        = flag.String("overlay", "", "text scrolling over all modes")
+		overlayOpacity = flag.Float64("overlayopacity", 0.8, "opacity of -overlay text")
+		text           = flag.String("text", "", "show text message, *words* are highlighted")
+		textEffects    = flag.String("texteffects", "scroll", "text effects: scroll,typewriter,wave,hue,vscroll,fade,blink")
 		audioDevice    = flag.String("audiodevice", "", "serial port of audio device")
 		port           = flag.String("port", "", "serial port")
 		joystick       = flag.String("joystick", "", "joystick ids")
 		term           = flag.Bool("term", false, "use terminal")
 	)
 
+	var pipeColors insta.ColorRules
+	flag.Var(&pipeColors, "pipecolor", "color rule for -pipe lines like 'ERROR=ff0000', can be repeated")
+
 	flag.Parse()
 
 	var (
 		c   insta.Client
 		err error
 	)
+	themeNames := strings.Split(*themes, ",")
+	for _, n := range themeNames {
+		if err := theme.Set(n); err != nil {
+			log.Fatal(err)
+		}
+		if *themeCycle > 0 {
+			// replace the theme by a copy, the predefined themes stay as
+			// they are
+			t := *theme.Current()
+			t.CycleEvery = *themeCycle
+			theme.Register(&t)
+		}
+	}
+	theme.Set(themeNames[0])
+
+	var sc *scene.Scene
+	if *sceneFile != "" {
+		sc, err = scene.Load(*sceneFile)
+		if errs, ok := err.(scene.ErrorList); ok {
+			for _, e := range errs {
+				fmt.Fprintf(os.Stderr, "%s:%s\n", *sceneFile, e)
+			}
+			os.Exit(1)
+		} else if err != nil {
+			log.Fatal(err)
+		}
+		if *sceneCheck {
+			return
+		}
+	}
+
 	if *term {
 		log.Println("using terminal")
 		c = insta.NewTerm()
@@ -99,7 +199,7 @@ func main() {
 		for _, idStr := range strings.Split(*joystick, ",") {
 			id, err := strconv.ParseInt(idStr, 10, 32)
 			if err != nil {
-				log.Fatalf("invalid -joystick option", err)
+				log.Fatalf("invalid -joystick option: %s", err)
 			}
 			ids = append(ids, int(id))
 		}
@@ -129,33 +229,280 @@ func main() {
 		}
 	}
 
+	fo, err := fit.Parse(*fitImages)
+	if err != nil {
+		log.Fatal(err)
+	}
+	scaleFilter, err := resample.ParseFilter(*filter)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if fo.Filter == resample.Auto {
+		fo.Filter = scaleFilter
+	}
+
+	var raw video.Raw
+	if *videoRaw != "" {
+		if raw, err = video.ParseRaw(*videoRaw); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	// recordings of LED matrices are pixel art, scale them without
+	// smoothing unless a filter was requested
+	recFit := fo
+	if recFit.Filter == resample.Auto {
+		recFit.Filter = resample.Nearest
+	}
+	var rec *anim.Clip
+	if *recFile != "" {
+		rf, err := ledrec.ParseFormat(*recFormat)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if rec, err = ledrec.Load(*recFile, rf); err != nil {
+			log.Fatal(err)
+		}
+		rec.Loops = 0
+	}
+
+	var mc *media.Cache
+	if *mediaCache != "" {
+		if mc, err = media.NewCache(*mediaCache, insta.ScreenWidth, insta.ScreenHeight, fo); err != nil {
+			log.Fatal(err)
+		}
+		mc.Watch([]string{"gifs", "images"}, 10*time.Second)
+	}
+	libraries := map[string]*media.Library{
+		"gifs":   media.NewLibrary("gifs"),
+		"images": media.NewLibrary("images"),
+	}
+	playMedia := func(dir, tag string, n int, d time.Duration) {
+		for i := 0; i < n; i++ {
+			fname, ok := libraries[dir].Next(tag)
+			if !ok {
+				log.Printf("no media in %s with tag %q", dir, tag)
+				return
+			}
+			insta.PlayMedia(c, mc, fname, d, fo)
+		}
+	}
+
+	effects, err := insta.ParseTextEffects(*textEffects)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	face, err := clock.ParseFace(*clockFace)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	var msgBoard *board.Board
+	if *messages != "" {
+		if *msgModerate && *msgToken == "" {
+			log.Fatal("-msgmoderate requires -msgtoken, otherwise anyone can moderate the messages")
+		}
+		msgBoard, err = board.NewBoard(*messages)
+		if err != nil {
+			log.Fatal(err)
+		}
+		msgBoard.RateLimit = *msgRate
+		msgBoard.Moderate = *msgModerate
+		if *msgBlocklist != "" {
+			msgBoard.Blocklist, err = board.LoadBlocklist(*msgBlocklist)
+			if err != nil {
+				log.Fatal(err)
+			}
+		}
+		if *msgTCP != "" {
+			go func() {
+				log.Fatal(msgBoard.ListenTCP(*msgTCP))
+			}()
+		}
+		if *msgHTTP != "" {
+			go func() {
+				log.Fatal(http.ListenAndServe(*msgHTTP, msgBoard.Handler(*msgToken)))
+			}()
+		}
+		if *msgStdin {
+			go func() {
+				if err := msgBoard.ReadLines(os.Stdin, "stdin"); err != nil {
+					log.Println("error: reading messages from stdin:", err)
+				}
+			}()
+		}
+	}
+
+	// showMessages plays the next message of the board between two modes
+	showMessages := func() {
+		if msgBoard == nil {
+			return
+		}
+		if m, ok := msgBoard.Next(); ok {
+			insta.PlayText(c, insta.NewText(m.Text, insta.TextScroll))
+		}
+	}
+
+	if *overlay != "" {
+		comp := layer.NewCompositor(c)
+		base := comp.Add("mode")
+		ov := comp.Add("overlay")
+		ov.Opacity = *overlayOpacity
+		ot := insta.NewText(*overlay, insta.TextScroll|insta.TextHue)
+		ov.Update = func(l *layer.Layer, el time.Duration) {
+			l.Clear()
+			ot.Draw(l, el%ot.Duration())
+		}
+		c = comp.Client(base)
+	}
+
+	var panVX, panVY float64
+	if runPan.Seconds() > 0 {
+		if *panImage == "" {
+			log.Fatal("-pan requires -panimage")
+		}
+		if _, err := fmt.Sscanf(*panSpeed, "%g,%g", &panVX, &panVY); err != nil {
+			log.Fatalf("invalid -panspeed %q: %s", *panSpeed, err)
+		}
+	}
+
+	var split *layout.Layout
+	if runLayout.Seconds() > 0 {
+		split, err = layout.Parse(*layoutSpec, func(name string, w, h int) (insta.Mode, error) {
+			switch name {
+			case "clock":
+				return clock.NewClock(face, *clockSeconds, *clockDate), nil
+			case "life":
+				return life.NewMode(), nil
+			case "rainbow":
+				return insta.NewRainbow(), nil
+			case "recording":
+				if rec == nil {
+					return nil, fmt.Errorf("layout region recording requires -recording")
+				}
+				return rec.Fit(w, h, recFit), nil
+			case "text":
+				if *text == "" {
+					return nil, fmt.Errorf("layout region text requires -text")
+				}
+				t := insta.NewText(*text, effects)
+				t.Width = w
+				return insta.ModeFunc(func(dst draw.Image, el time.Duration) {
+					t.Draw(dst, el%t.Duration())
+				}), nil
+			}
+			return nil, fmt.Errorf("unknown layout mode %q", name)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+
 	c.SetFPS(*fps)
 	go c.Run()
 
 	c.SetAfterglow(0.3)
 
 	if *runServer {
-		srv.Server(c)
+		srv.Server(c, fo)
 		return
 	}
 
-	for {
+	if *videoFile == "-" {
+		insta.PlayVideo(c, "-", raw, 0, fo)
+		return
+	}
+
+	if *runPipe {
+		p := insta.NewPipe()
+		p.Rules = pipeColors
+		p.ScrollSpeed = *pipeSpeed
+		if err := p.Run(c, os.Stdin); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	if *countdown != "" {
+		end, err := clock.ParseTarget(*countdown, time.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+		clock.NewCountdown(end).Run(c)
+	}
+
+	for round := 0; ; round++ {
+		theme.Set(themeNames[round%len(themeNames)])
+
 		if runRainbow.Seconds() > 0 {
 			insta.Rainbow(c, *runRainbow)
 		}
+		showMessages()
+
+		if sc != nil {
+			sc.Play(c, *sceneDuration)
+		}
+		showMessages()
+
+		if *text != "" {
+			insta.PlayText(c, insta.NewText(strings.Replace(*text, `\n`, "\n", -1), effects))
+		}
+		showMessages()
 
 		if *runLogo {
 			c.SetAfterglow(0)
-			insta.ScrollImage(c, "img/mainframe-mod.png")
+			insta.ScrollImage(c, "img/mainframe-mod.png", scaleFilter)
 			c.SetAfterglow(0.4)
 		}
+		showMessages()
+
+		if runPan.Seconds() > 0 {
+			insta.PanImage(c, *panImage, panVX, panVY, *runPan, scaleFilter)
+		}
+		showMessages()
 
 		if runGifs.Seconds() > 0 {
-			insta.RandomGif(c, "gifs", *runGifs)
+			playMedia("gifs", *gifTag, *gifCount, *runGifs)
 			time.Sleep(100 * time.Millisecond)
 		}
+		showMessages()
+
+		if runImages.Seconds() > 0 {
+			playMedia("images", *imageTag, 1, *runImages)
+		}
+		showMessages()
+
+		if *videoFile != "" {
+			insta.PlayVideo(c, *videoFile, raw, *runVideo, fo)
+		}
+		showMessages()
 
-		if runLife.Seconds() > 0 {
+		if rec != nil {
+			d := *runRecording
+			if d == 0 {
+				d = rec.Duration()
+			}
+			insta.RunMode(c, rec.Fit(insta.ScreenWidth, insta.ScreenHeight, recFit), d)
+		}
+		showMessages()
+
+		if runSlideshow.Seconds() > 0 {
+			ss := insta.NewSlideshow(libraries["images"])
+			ss.Tag = *imageTag
+			ss.Show, ss.Fade = *slideTime, *slideFade
+			ss.Captions = *slideCaptions
+			ss.Filter = fo.Filter
+			ss.Play(c, *runSlideshow)
+		}
+		showMessages()
+
+		if runLife.Seconds() > 0 && *lifeWidth > insta.ScreenWidth {
+			v := insta.NewViewport(*lifeWidth, insta.ScreenHeight, life.NewMode())
+			v.Camera.Wrap = true
+			v.Camera.VX = 10
+			insta.RunMode(c, v, *runLife)
+		} else if runLife.Seconds() > 0 {
 			l := life.NewLife(insta.ScreenWidth, insta.ScreenHeight)
 			t := time.NewTicker(2 * time.Second)
 
@@ -187,11 +534,13 @@ func main() {
 
 			t.Stop()
 		}
+		showMessages()
 
 		if runGifs.Seconds() > 0 {
-			insta.RandomGif(c, "gifs", *runGifs)
+			playMedia("gifs", *gifTag, *gifCount, *runGifs)
 			time.Sleep(200 * time.Millisecond)
 		}
+		showMessages()
 
 		if runAudio.Seconds() > 0 && audioGraph != nil {
 			till := time.Now().Add(*runAudio)
@@ -205,43 +554,35 @@ func main() {
 				time.Sleep(50 * time.Millisecond)
 			}
 		}
+		showMessages()
 
 		if runGifs.Seconds() > 0 {
-			insta.RandomGif(c, "gifs", *runGifs)
+			playMedia("gifs", *gifTag, *gifCount, *runGifs)
 			time.Sleep(200 * time.Millisecond)
 		}
+		showMessages()
+
+		if runClock.Seconds() > 0 {
+			insta.RunMode(c, clock.NewClock(face, *clockSeconds, *clockDate), *runClock)
+		}
+		showMessages()
+
+		if split != nil {
+			insta.RunMode(c, split, *runLayout)
+		}
+		showMessages()
 
 		if runSnake.Seconds() > 0 {
 			c.SetAfterglow(0.2)
-			sn := snake.NewGame(insta.ScreenWidth, insta.ScreenHeight, *runSnake)
-		SnakeLoop:
-			for {
-				s := insta.NewScreen()
-				sn.Init()
-				for {
-					status := sn.Step(pads())
-					sn.Paint(s)
-					c.SetScreenImmediate(s)
-					if status == snake.End {
-						time.Sleep(250 * time.Millisecond)
-						// wait to prevent score screen from being overdrawn
-						// by last game screen
-						sn.PaintScore(s)
-						c.SetScreenImmediate(s)
-						time.Sleep(3 * time.Second)
-						break
-					}
-					if status == snake.Exit {
-						break SnakeLoop
-					}
-				}
-			}
+			snake.NewGame(insta.ScreenWidth, insta.ScreenHeight, *runSnake).Play(c, pads)
 			c.SetAfterglow(0.3)
 		}
+		showMessages()
 
 		if runSpaceflight.Seconds() > 0 {
 			insta.Spaceflight(c, *runSpaceflight)
 		}
+		showMessages()
 		time.Sleep(20 * time.Millisecond)
 	}
 }
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"image/draw"
+	"log"
+	"math/rand"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/clock"
+	"github.com/ktt-ol/go-insta/fit"
+	"github.com/ktt-ol/go-insta/layout"
+	"github.com/ktt-ol/go-insta/ledrec"
+	"github.com/ktt-ol/go-insta/life"
+	"github.com/ktt-ol/go-insta/media"
+	"github.com/ktt-ol/go-insta/render"
+	"github.com/ktt-ol/go-insta/resample"
+	"github.com/ktt-ol/go-insta/scene"
+	"github.com/ktt-ol/go-insta/snake"
+	"github.com/ktt-ol/go-insta/theme"
+	"github.com/ktt-ol/go-insta/timing"
+	"github.com/ktt-ol/go-insta/video"
+)
+
+const renderUsage = `usage: insta render [flags] mode [arg]
+
+Renders a mode offline with a virtual clock and writes it as animated gif
+or png sequence. Modes:
+
+  clock, life, rainbow, spaceflight
+  snake            game without players, till -duration
+  countdown TARGET duration (10m) or time of day (18:30)
+  text MESSAGE     repeated till -duration
+  scene FILE.json
+  image FILE       image, gif or animated png
+  pan FILE         scroll a panorama image, see -panspeed
+  pipe FILE        scroll the lines of FILE, - reads stdin
+  slideshow DIR    images of DIR with pan and zoom
+  video FILE.y4m
+  recording FILE   Glediator or Jinx! recording, see -recformat
+  layout SPEC      split screen like -layoutspec, regions clock, life and
+                   rainbow
+
+The audio graph, the random gifs directory and the message board need
+hardware or live input and are not supported.
+
+Flags:
+`
+
+// renderMain implements "insta render". Modes run against a virtual clock
+// that advances by one frame with each screen, so rendering is faster than
+// real time and reproducible with the same seed and start time.
+func renderMain(args []string) {
+	fs := flag.NewFlagSet("render", flag.ExitOnError)
+	var (
+		out         = fs.String("o", "out.gif", "output file, .gif or .png for a png sequence like frames/%04d.png")
+		fps         = fs.Int("fps", 25, "frames per second")
+		duration    = fs.Duration("duration", 5*time.Second, "length of the recording")
+		seed        = fs.Int64("seed", 1, "random seed")
+		start       = fs.String("time", "2020-01-01T12:00:00Z", "start time of the virtual clock, RFC 3339")
+		scale       = fs.Int("scale", 1, "size of each LED in pixels")
+		dots        = fs.Bool("dots", false, "draw LEDs as round dots with gaps, needs -scale 3 or more")
+		themeName   = fs.String("theme", "default", "color theme: "+strings.Join(theme.Names(), ", "))
+		clockFace   = fs.String("clockface", "digital", "clock face: digital, analog, binary or words")
+		textEffects = fs.String("texteffects", "scroll", "text effects: scroll,typewriter,wave,hue,vscroll,fade,blink")
+		fitImages   = fs.String("fit", "stretch", "fit of images and videos: stretch, contain, cover, center or smart, optionally followed by a background color and a filter")
+		recFormat   = fs.String("recformat", "16x16@25", "matrix size, frame rate and options of recordings like '16x16@30 glediator serpentine grb'")
+		panSpeed    = fs.String("panspeed", "15,0", "x,y velocity of pan in pixels per second")
+	)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), renderUsage)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() < 1 || *fps <= 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+	mode, arg := fs.Arg(0), fs.Arg(1)
+	unsupported := map[string]string{
+		"audio": "the audio graph needs the audio device",
+		"gifs":  "the gifs directory is played at random, render single gifs with image",
+		"board": "the message board needs live submissions, render messages with text",
+	}
+	if why, ok := unsupported[mode]; ok {
+		log.Fatalf("render mode %s is not supported: %s", mode, why)
+	}
+	needArg := map[string]bool{"countdown": true, "text": true, "scene": true, "image": true,
+		"pan": true, "pipe": true, "slideshow": true, "video": true, "recording": true, "layout": true}
+	if needArg[mode] && arg == "" {
+		log.Fatalf("render mode %s requires an argument", mode)
+	}
+
+	t, err := time.Parse(time.RFC3339, *start)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := theme.Set(*themeName); err != nil {
+		log.Fatal(err)
+	}
+	fo, err := fit.Parse(*fitImages)
+	if err != nil {
+		log.Fatal(err)
+	}
+	rand.Seed(*seed)
+	vc := timing.NewVirtual(t)
+	timing.Set(vc)
+	rec := render.NewRecorder(vc, *fps)
+	face, err := clock.ParseFace(*clockFace)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	switch mode {
+	case "clock":
+		insta.RunMode(rec, clock.NewClock(face, false, false), *duration)
+	case "life":
+		insta.RunMode(rec, life.NewMode(), *duration)
+	case "rainbow":
+		insta.RunMode(rec, insta.NewRainbow(), *duration)
+	case "spaceflight":
+		insta.Spaceflight(rec, *duration)
+	case "snake":
+		pads := func() []insta.Pad { return []insta.Pad{&insta.NullPad{}, &insta.NullPad{}} }
+		snake.NewGame(insta.ScreenWidth, insta.ScreenHeight, *duration).Play(rec, pads)
+	case "countdown":
+		end, err := clock.ParseTarget(arg, vc.Now())
+		if err != nil {
+			log.Fatal(err)
+		}
+		insta.RunMode(rec, clock.NewCountdown(end), *duration)
+	case "text":
+		effects, err := insta.ParseTextEffects(*textEffects)
+		if err != nil {
+			log.Fatal(err)
+		}
+		t := insta.NewText(strings.Replace(arg, `\n`, "\n", -1), effects)
+		insta.RunMode(rec, insta.ModeFunc(func(dst draw.Image, el time.Duration) {
+			t.Draw(dst, el%t.Duration())
+		}), *duration)
+	case "scene":
+		sc, err := scene.Load(arg)
+		if err != nil {
+			log.Fatal(err)
+		}
+		sc.Play(rec, *duration)
+	case "image":
+		insta.PlayMedia(rec, nil, arg, *duration, fo)
+	case "pan":
+		var vx, vy float64
+		if _, err := fmt.Sscanf(*panSpeed, "%g,%g", &vx, &vy); err != nil {
+			log.Fatalf("invalid -panspeed %q: %s", *panSpeed, err)
+		}
+		insta.PanImage(rec, arg, vx, vy, *duration, fo.Filter)
+	case "pipe":
+		r := os.Stdin
+		if arg != "-" {
+			r, err = os.Open(arg)
+			if err != nil {
+				log.Fatal(err)
+			}
+			defer r.Close()
+		}
+		if err := insta.NewPipe().Run(rec, r); err != nil {
+			log.Fatal(err)
+		}
+	case "slideshow":
+		ss := insta.NewSlideshow(media.NewLibrary(arg))
+		ss.Filter = fo.Filter
+		ss.Play(rec, *duration)
+	case "video":
+		insta.PlayVideo(rec, arg, video.Raw{}, *duration, fo)
+	case "recording":
+		rf, err := ledrec.ParseFormat(*recFormat)
+		if err != nil {
+			log.Fatal(err)
+		}
+		clip, err := ledrec.Load(arg, rf)
+		if err != nil {
+			log.Fatal(err)
+		}
+		clip.Loops = 0
+		// recordings are pixel art, like in the main command
+		if fo.Filter == resample.Auto {
+			fo.Filter = resample.Nearest
+		}
+		insta.RunMode(rec, clip.Fit(insta.ScreenWidth, insta.ScreenHeight, fo), *duration)
+	case "layout":
+		split, err := layout.Parse(arg, func(name string, w, h int) (insta.Mode, error) {
+			switch name {
+			case "clock":
+				return clock.NewClock(face, false, false), nil
+			case "life":
+				return life.NewMode(), nil
+			case "rainbow":
+				return insta.NewRainbow(), nil
+			}
+			return nil, fmt.Errorf("unknown layout mode %q, render supports clock, life and rainbow", name)
+		})
+		if err != nil {
+			log.Fatal(err)
+		}
+		insta.RunMode(rec, split, *duration)
+	default:
+		log.Fatalf("unknown render mode %q", mode)
+	}
+
+	frames := rec.Until(*duration)
+	st := render.Style{Scale: *scale, Dots: *dots}
+	if strings.HasSuffix(strings.ToLower(*out), ".gif") {
+		err = render.WriteGIF(*out, frames, *duration, st)
+	} else {
+		err = render.WritePNGs(*out, frames, *duration, *fps, st)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
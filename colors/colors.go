@@ -0,0 +1,106 @@
+// Package colors converts between sRGB, linear light, HSV, HSL and OKLab and
+// interpolates colors in linear light.
+//
+// sRGB bytes are not proportional to the emitted light. Mixing them directly
+// darkens crossfades in the middle, mixing in linear light does not. The
+// conversions between bytes and linear values use lookup tables, as they are
+// used for every pixel of every frame.
+package colors
+
+import (
+	"image/color"
+	"math"
+)
+
+// linearBits is the precision of the table for linear to sRGB conversions.
+// 12 bit are enough to get the same byte as the exact conversion, except
+// for a few dark values that are off by one.
+const linearBits = 12
+
+var (
+	toLinear   [256]float32
+	fromLinear [1<<linearBits + 1]uint8
+)
+
+func init() {
+	for i := range toLinear {
+		toLinear[i] = float32(SRGBToLinear(float64(i) / 255))
+	}
+	for i := range fromLinear {
+		fromLinear[i] = uint8(LinearToSRGB(float64(i)/(1<<linearBits))*255 + 0.5)
+	}
+}
+
+// SRGBToLinear decodes an sRGB value (0-1) to linear light.
+func SRGBToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// LinearToSRGB encodes linear light (0-1) as sRGB value.
+func LinearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// ToLinear returns the linear light of an sRGB byte.
+func ToLinear(v uint8) float32 {
+	return toLinear[v]
+}
+
+// FromLinear returns the sRGB byte of linear light. Values outside of 0-1
+// are clamped.
+func FromLinear(v float32) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 1 {
+		return 255
+	}
+	return fromLinear[int(v*(1<<linearBits)+0.5)]
+}
+
+// Linear returns the linear light RGB values of c. The alpha of c is
+// ignored.
+func Linear(c color.Color) (r, g, b float32) {
+	cr, cg, cb, _ := c.RGBA()
+	return toLinear[cr>>8], toLinear[cg>>8], toLinear[cb>>8]
+}
+
+// FromLinearRGB returns the opaque sRGB color of linear light RGB values.
+func FromLinearRGB(r, g, b float32) color.RGBA {
+	return color.RGBA{FromLinear(r), FromLinear(g), FromLinear(b), 255}
+}
+
+// Lerp interpolates between a (t=0) and b (t=1) in linear light. Alpha is
+// interpolated linearly.
+func Lerp(a, b color.RGBA, t float64) color.RGBA {
+	tf := float32(t)
+	return color.RGBA{
+		lerp8(a.R, b.R, tf),
+		lerp8(a.G, b.G, tf),
+		lerp8(a.B, b.B, tf),
+		uint8(float32(a.A) + (float32(b.A)-float32(a.A))*tf + 0.5),
+	}
+}
+
+// LerpBytes interpolates each sRGB byte of a and b in linear light and
+// stores the results in dst, e.g. the Pix of two screens.
+func LerpBytes(dst, a, b []uint8, t float64) {
+	tf := float32(t)
+	for i := range dst {
+		dst[i] = lerp8(a[i], b[i], tf)
+	}
+}
+
+func lerp8(a, b uint8, t float32) uint8 {
+	if a == b {
+		return a
+	}
+	la, lb := toLinear[a], toLinear[b]
+	return FromLinear(la + (lb-la)*t)
+}
@@ -0,0 +1,135 @@
+package colors
+
+import (
+	"image/color"
+	"math"
+)
+
+// HSVToRGB converts hue (0-360), saturation and value (0-1) to RGB (0-1).
+func HSVToRGB(h, s, v float64) (r, g, b float64) {
+	if s == 0 {
+		// achromatic (grey)
+		return v, v, v
+	}
+	h = math.Mod(h, 360)
+	if h < 0 {
+		h += 360
+	}
+	h = h / 60 // sector 0 to 5
+	i := math.Floor(h)
+	f := h - i
+	p := v * (1 - s)
+	q := v * (1 - s*f)
+	t := v * (1 - s*(1-f))
+
+	switch i {
+	case 0:
+		return v, t, p
+	case 1:
+		return q, v, p
+	case 2:
+		return p, v, t
+	case 3:
+		return p, q, v
+	case 4:
+		return t, p, v
+	default: // case 5:
+		return v, p, q
+	}
+}
+
+// RGBToHSV converts RGB (0-1) to hue (0-360), saturation and value (0-1).
+func RGBToHSV(r, g, b float64) (h, s, v float64) {
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	v = max
+	d := max - min
+	if max > 0 {
+		s = d / max
+	}
+	return hue(r, g, b, max, d), s, v
+}
+
+// HSLToRGB converts hue (0-360), saturation and lightness (0-1) to RGB
+// (0-1).
+func HSLToRGB(h, s, l float64) (r, g, b float64) {
+	v := l + s*math.Min(l, 1-l)
+	sv := 0.0
+	if v > 0 {
+		sv = 2 * (1 - l/v)
+	}
+	return HSVToRGB(h, sv, v)
+}
+
+// RGBToHSL converts RGB (0-1) to hue (0-360), saturation and lightness
+// (0-1).
+func RGBToHSL(r, g, b float64) (h, s, l float64) {
+	max := math.Max(r, math.Max(g, b))
+	min := math.Min(r, math.Min(g, b))
+	d := max - min
+	l = (max + min) / 2
+	if l > 0 && l < 1 {
+		s = d / (1 - math.Abs(2*l-1))
+	}
+	return hue(r, g, b, max, d), s, l
+}
+
+func hue(r, g, b, max, d float64) float64 {
+	if d == 0 {
+		return 0
+	}
+	var h float64
+	switch max {
+	case r:
+		h = math.Mod((g-b)/d, 6)
+	case g:
+		h = (b-r)/d + 2
+	default:
+		h = (r-g)/d + 4
+	}
+	h *= 60
+	if h < 0 {
+		h += 360
+	}
+	return h
+}
+
+// HSV returns the opaque color of hue (0-360), saturation and value (0-1).
+func HSV(h, s, v float64) color.RGBA {
+	return rgb8(HSVToRGB(h, s, v))
+}
+
+// HSL returns the opaque color of hue (0-360), saturation and lightness
+// (0-1).
+func HSL(h, s, l float64) color.RGBA {
+	return rgb8(HSLToRGB(h, s, l))
+}
+
+// ToHSV returns hue (0-360), saturation and value (0-1) of c.
+func ToHSV(c color.Color) (h, s, v float64) {
+	return RGBToHSV(rgb(c))
+}
+
+// ToHSL returns hue (0-360), saturation and lightness (0-1) of c.
+func ToHSL(c color.Color) (h, s, l float64) {
+	return RGBToHSL(rgb(c))
+}
+
+func rgb(c color.Color) (r, g, b float64) {
+	cr, cg, cb, _ := c.RGBA()
+	return float64(cr) / 0xffff, float64(cg) / 0xffff, float64(cb) / 0xffff
+}
+
+func rgb8(r, g, b float64) color.RGBA {
+	return color.RGBA{clamp8(r * 255), clamp8(g * 255), clamp8(b * 255), 255}
+}
+
+func clamp8(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
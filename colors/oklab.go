@@ -0,0 +1,59 @@
+package colors
+
+import (
+	"image/color"
+	"math"
+)
+
+// OKLab is a perceptual color space. Equal distances look like equal
+// differences, which makes it a good space for gradients and hue shifts.
+// L is the lightness (0-1), A and B are green-red and blue-yellow.
+type OKLab struct {
+	L, A, B float64
+}
+
+// ToOKLab converts c to OKLab.
+func ToOKLab(c color.Color) OKLab {
+	r, g, b := Linear(c)
+	return LinearToOKLab(float64(r), float64(g), float64(b))
+}
+
+// LinearToOKLab converts linear light RGB to OKLab.
+func LinearToOKLab(r, g, b float64) OKLab {
+	l := math.Cbrt(0.4122214708*r + 0.5363325363*g + 0.0514459929*b)
+	m := math.Cbrt(0.2119034982*r + 0.6806995451*g + 0.1073969566*b)
+	s := math.Cbrt(0.0883024619*r + 0.2817188376*g + 0.6299787005*b)
+	return OKLab{
+		L: 0.2104542553*l + 0.7936177850*m - 0.0040720468*s,
+		A: 1.9779984951*l - 2.4285922050*m + 0.4505937099*s,
+		B: 0.0259040371*l + 0.7827717662*m - 0.8086757660*s,
+	}
+}
+
+// Linear converts the color to linear light RGB. Colors outside of the sRGB
+// gamut return values outside of 0-1.
+func (c OKLab) Linear() (r, g, b float64) {
+	l := c.L + 0.3963377774*c.A + 0.2158037573*c.B
+	m := c.L - 0.1055613458*c.A - 0.0638541728*c.B
+	s := c.L - 0.0894841775*c.A - 1.2914855480*c.B
+	l, m, s = l*l*l, m*m*m, s*s*s
+	return 4.0767416621*l - 3.3077115913*m + 0.2309699292*s,
+		-1.2684380046*l + 2.6097574011*m - 0.3413193965*s,
+		-0.0041960863*l - 0.7034186147*m + 1.7076147010*s
+}
+
+// Color returns the opaque sRGB color, clipped to the sRGB gamut.
+func (c OKLab) Color() color.RGBA {
+	r, g, b := c.Linear()
+	return FromLinearRGB(float32(r), float32(g), float32(b))
+}
+
+// LerpOKLab interpolates between a (t=0) and b (t=1) in OKLab.
+func LerpOKLab(a, b color.Color, t float64) color.RGBA {
+	ca, cb := ToOKLab(a), ToOKLab(b)
+	return OKLab{
+		L: ca.L + (cb.L-ca.L)*t,
+		A: ca.A + (cb.A-ca.A)*t,
+		B: ca.B + (cb.B-ca.B)*t,
+	}.Color()
+}
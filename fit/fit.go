@@ -0,0 +1,213 @@
+// Package fit scales images to a target size while keeping their aspect
+// ratio.
+package fit
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"strconv"
+	"strings"
+
+	"github.com/ktt-ol/go-insta/resample"
+)
+
+type Mode int
+
+const (
+	// Stretch scales the image to the target size, ignoring the aspect
+	// ratio.
+	Stretch Mode = iota
+	// Contain scales the image to fit completely into the target, with
+	// bars in the background color.
+	Contain
+	// Cover scales the image to fill the target and crops the center.
+	Cover
+	// Center shows the center of the image without scaling.
+	Center
+	// Smart scales like Cover, but crops toward the most detailed region.
+	Smart
+)
+
+var modeNames = []string{"stretch", "contain", "cover", "center", "smart"}
+
+func (m Mode) String() string {
+	if int(m) < len(modeNames) {
+		return modeNames[m]
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ParseMode returns the mode for the name as returned by Mode.String.
+func ParseMode(s string) (Mode, error) {
+	for i, n := range modeNames {
+		if n == s {
+			return Mode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown fit mode %q, expected one of %s", s, strings.Join(modeNames, ", "))
+}
+
+// Options are the fit mode, the background color for the bars of Contain
+// and for small images with Center, and the filter used for scaling.
+type Options struct {
+	Mode       Mode
+	Background color.RGBA
+	Filter     resample.Filter
+}
+
+// Parse parses options like "contain", "contain 202040" or "cover box", a
+// mode optionally followed by the background color as hex value and the
+// resample filter.
+func Parse(s string) (Options, error) {
+	o := Options{Background: color.RGBA{0, 0, 0, 255}}
+	f := strings.Fields(s)
+	if len(f) == 0 || len(f) > 3 {
+		return o, fmt.Errorf("invalid fit %q, expected mode, optional background color and filter", s)
+	}
+	var err error
+	if o.Mode, err = ParseMode(f[0]); err != nil {
+		return o, err
+	}
+	for _, a := range f[1:] {
+		if filter, err := resample.ParseFilter(a); err == nil {
+			o.Filter = filter
+			continue
+		}
+		hex := strings.TrimPrefix(a, "#")
+		v, err := strconv.ParseUint(hex, 16, 32)
+		if err != nil || len(hex) != 6 {
+			return o, fmt.Errorf("invalid background color or filter %q, expected RRGGBB or filter name", a)
+		}
+		o.Background = color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}
+	}
+	return o, nil
+}
+
+func (o Options) String() string {
+	return fmt.Sprintf("%s %02x%02x%02x %s", o.Mode, o.Background.R, o.Background.G, o.Background.B, o.Filter)
+}
+
+// Image returns img fitted into an image of the size w x h.
+func Image(img image.Image, w, h int, o Options) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(dst, dst.Bounds(), image.NewUniform(o.Background), image.ZP, draw.Src)
+	b := img.Bounds()
+	iw, ih := b.Dx(), b.Dy()
+	if iw == 0 || ih == 0 || w == 0 || h == 0 {
+		return dst
+	}
+
+	switch o.Mode {
+	case Contain:
+		sw, sh := w, ih*w/iw
+		if sh > h {
+			sw, sh = iw*h/ih, h
+		}
+		scaled := scale(img, sw, sh, o.Filter)
+		r := image.Rect(0, 0, sw, sh).Add(image.Pt((w-sw)/2, (h-sh)/2))
+		draw.Draw(dst, r, scaled, scaled.Bounds().Min, draw.Over)
+	case Cover, Smart:
+		sw, sh := w, ih*w/iw
+		if sh < h {
+			sw, sh = iw*h/ih, h
+		}
+		scaled := scale(img, sw, sh, o.Filter)
+		off := image.Pt((sw-w)/2, (sh-h)/2)
+		if o.Mode == Smart {
+			off = detailOffset(scaled, w, h)
+		}
+		draw.Draw(dst, dst.Bounds(), scaled, scaled.Bounds().Min.Add(off), draw.Over)
+	case Center:
+		p := b.Min.Add(image.Pt((iw-w)/2, (ih-h)/2))
+		draw.Draw(dst, dst.Bounds(), img, p, draw.Over)
+	default:
+		scaled := scale(img, w, h, o.Filter)
+		draw.Draw(dst, dst.Bounds(), scaled, scaled.Bounds().Min, draw.Over)
+	}
+	return dst
+}
+
+func scale(img image.Image, w, h int, f resample.Filter) image.Image {
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	b := img.Bounds()
+	if b.Dx() == w && b.Dy() == h {
+		return img
+	}
+	return resample.Resize(img, w, h, f)
+}
+
+// detailOffset returns the offset of the w x h window of img with the
+// most detail, measured as sum of the luminance differences to the right
+// and lower neighbours. img is larger than the window in at most one
+// direction.
+func detailOffset(img image.Image, w, h int) image.Point {
+	b := img.Bounds()
+	iw, ih := b.Dx(), b.Dy()
+	lum := make([]float64, iw*ih)
+	for y := 0; y < ih; y++ {
+		for x := 0; x < iw; x++ {
+			r, g, bl, _ := img.At(b.Min.X+x, b.Min.Y+y).RGBA()
+			lum[y*iw+x] = 0.299*float64(r) + 0.587*float64(g) + 0.114*float64(bl)
+		}
+	}
+	// detail per column and per row
+	cols := make([]float64, iw)
+	rows := make([]float64, ih)
+	for y := 0; y < ih; y++ {
+		for x := 0; x < iw; x++ {
+			v := lum[y*iw+x]
+			var d float64
+			if x+1 < iw {
+				d += abs(v - lum[y*iw+x+1])
+			}
+			if y+1 < ih {
+				d += abs(v - lum[(y+1)*iw+x])
+			}
+			cols[x] += d
+			rows[y] += d
+		}
+	}
+	return image.Pt(bestWindow(cols, w), bestWindow(rows, h))
+}
+
+// bestWindow returns the start of the window of size n with the largest
+// sum. Ties prefer the window closest to the center.
+func bestWindow(v []float64, n int) int {
+	if len(v) <= n {
+		return 0
+	}
+	var sum float64
+	for _, x := range v[:n] {
+		sum += x
+	}
+	center := (len(v) - n) / 2
+	best, bestSum := 0, sum
+	for i := 1; i+n <= len(v); i++ {
+		sum += v[i+n-1] - v[i-1]
+		if sum > bestSum || sum == bestSum && absInt(i-center) < absInt(best-center) {
+			best, bestSum = i, sum
+		}
+	}
+	return best
+}
+
+func abs(v float64) float64 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+func absInt(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
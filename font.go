@@ -0,0 +1,104 @@
+package insta
+
+import (
+	"image"
+
+	"golang.org/x/image/font/basicfont"
+)
+
+// Face3x5 is a tiny font for single lines of text (dates, scores, labels).
+// It holds the printable ASCII characters from space to underscore,
+// lowercase letters are drawn as uppercase.
+var Face3x5 = &basicfont.Face{
+	Advance: 4,
+	Width:   3,
+	Height:  6,
+	Ascent:  5,
+	Descent: 1,
+	Mask:    mask3x5(),
+	Ranges: []basicfont.Range{
+		{Low: ' ', High: '`', Offset: 0},
+		{Low: 'a', High: '{', Offset: 'A' - ' '},
+	},
+}
+
+// glyphs3x5 contains the glyphs from ' ' to '_', five rows with three
+// pixels each.
+var glyphs3x5 = [...]string{
+	"...............", // space
+	".#..#..#.....#.", // !
+	"#.##.#.........", // "
+	"#.#####.#####.#", // #
+	".####..#..####.", // $
+	"#....#.#.#....#", // %
+	".#.#.#.#.#.#.##", // &
+	".#..#..........", // '
+	"..#.#..#..#...#", // (
+	"#...#..#..#.#..", // )
+	"...#.#.#.#.#...", // *
+	"....#.###.#....", // +
+	"..........#.#..", // ,
+	"......###......", // -
+	".............#.", // .
+	"..#..#.#.#..#..", // /
+	"####.##.##.####", // 0
+	".#.##..#..#.###", // 1
+	"###..#####..###", // 2
+	"###..#.##..####", // 3
+	"#.##.####..#..#", // 4
+	"####..###..####", // 5
+	"####..####.####", // 6
+	"###..#..#.#..#.", // 7
+	"####.#####.####", // 8
+	"####.####..####", // 9
+	"....#.....#....", // :
+	"....#.....#.#..", // ;
+	"..#.#.#...#...#", // <
+	"...###...###...", // =
+	"#...#...#.#.#..", // >
+	"###..#.##....#.", // ?
+	".#.#.#####...##", // @
+	".#.#.#####.##.#", // A
+	"##.#.###.#.###.", // B
+	".###..#..#...##", // C
+	"##.#.##.##.###.", // D
+	"####..##.#..###", // E
+	"####..##.#..#..", // F
+	".###..#.##.#.##", // G
+	"#.##.#####.##.#", // H
+	"###.#..#..#.###", // I
+	"..#..#..##.#.#.", // J
+	"#.##.###.#.##.#", // K
+	"#..#..#..#..###", // L
+	"#.########.##.#", // M
+	"##.#.##.##.##.#", // N
+	".#.#.##.##.#.#.", // O
+	"##.#.###.#..#..", // P
+	".#.#.##.###..##", // Q
+	"##.#.###.#.##.#", // R
+	".###...#...###.", // S
+	"###.#..#..#..#.", // T
+	"#.##.##.##.####", // U
+	"#.##.##.##.#.#.", // V
+	"#.##.########.#", // W
+	"#.##.#.#.#.##.#", // X
+	"#.##.#.#..#..#.", // Y
+	"###..#.#.#..###", // Z
+	"##.#..#..#..##.", // [
+	"#..#...#...#..#", // \
+	".##..#..#..#.##", // ]
+	".#.#.#.........", // ^
+	"............###", // _
+}
+
+func mask3x5() *image.Alpha {
+	m := image.NewAlpha(image.Rect(0, 0, 3, 6*len(glyphs3x5)))
+	for i, g := range glyphs3x5 {
+		for p, c := range g {
+			if c == '#' {
+				m.Pix[m.PixOffset(p%3, i*6+p/3)] = 0xff
+			}
+		}
+	}
+	return m
+}
@@ -0,0 +1,21 @@
+package gfx
+
+import (
+	"image"
+	"image/draw"
+)
+
+// Blit draws src with its top left corner at p over dst. The alpha of src
+// is multiplied with opacity (0-1).
+func Blit(dst draw.Image, p image.Point, src image.Image, opacity float64) {
+	sb := src.Bounds()
+	r := image.Rectangle{Min: p, Max: p.Add(sb.Size())}.Intersect(dst.Bounds())
+	if opacity <= 0 {
+		return
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			Blend(dst, x, y, src.At(sb.Min.X+x-p.X, sb.Min.Y+y-p.Y), opacity)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Package gfx provides drawing primitives for Screen and other draw.Image
+// targets. All operations are clipped to the bounds of the target.
+package gfx
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// rgba returns c as non-alpha-premultiplied 8 bit values.
+func rgba(c color.Color) (r, g, b, a float64) {
+	cr, cg, cb, ca := c.RGBA()
+	if ca == 0 {
+		return 0, 0, 0, 0
+	}
+	return float64(cr) / float64(ca) * 255, float64(cg) / float64(ca) * 255,
+		float64(cb) / float64(ca) * 255, float64(ca) / 0xffff
+}
+
+func clamp8(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+// Blend draws c with an additional coverage a (0-1) over the pixel at x/y.
+// The alpha of c is respected.
+func Blend(dst draw.Image, x, y int, c color.Color, a float64) {
+	if !image.Pt(x, y).In(dst.Bounds()) {
+		return
+	}
+	r, g, b, ca := rgba(c)
+	a *= ca
+	if a <= 0 {
+		return
+	}
+	if a >= 1 {
+		dst.Set(x, y, color.RGBA{clamp8(r), clamp8(g), clamp8(b), 255})
+		return
+	}
+	dr, dg, db, da := dst.At(x, y).RGBA()
+	dst.Set(x, y, color.RGBA{
+		clamp8(r*a + float64(dr>>8)*(1-a)),
+		clamp8(g*a + float64(dg>>8)*(1-a)),
+		clamp8(b*a + float64(db>>8)*(1-a)),
+		clamp8(255*a + float64(da>>8)*(1-a)),
+	})
+}
+
+// Set sets the pixel at x/y to c, if it is inside of the bounds.
+func Set(dst draw.Image, x, y int, c color.Color) {
+	if image.Pt(x, y).In(dst.Bounds()) {
+		dst.Set(x, y, c)
+	}
+}
+
+// Fill fills the whole image with c.
+func Fill(dst draw.Image, c color.Color) {
+	FillRect(dst, dst.Bounds(), c)
+}
+
+// FillRect fills r with c.
+func FillRect(dst draw.Image, r image.Rectangle, c color.Color) {
+	r = r.Intersect(dst.Bounds())
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			dst.Set(x, y, c)
+		}
+	}
+}
+
+// Rect draws the outline of r with c.
+func Rect(dst draw.Image, r image.Rectangle, c color.Color) {
+	if r.Empty() {
+		return
+	}
+	FillRect(dst, image.Rect(r.Min.X, r.Min.Y, r.Max.X, r.Min.Y+1), c)
+	FillRect(dst, image.Rect(r.Min.X, r.Max.Y-1, r.Max.X, r.Max.Y), c)
+	FillRect(dst, image.Rect(r.Min.X, r.Min.Y, r.Min.X+1, r.Max.Y), c)
+	FillRect(dst, image.Rect(r.Max.X-1, r.Min.Y, r.Max.X, r.Max.Y), c)
+}
+
+// clipT clips the line to the rectangle [minX, maxX]x[minY, maxY] with
+// the Liang-Barsky algorithm. It returns the part of the line inside as
+// the range t0-t1 of 0-1, or false if the line is outside.
+func clipT(x0, y0, x1, y1, minX, minY, maxX, maxY float64) (float64, float64, bool) {
+	t0, t1 := 0.0, 1.0
+	dx, dy := x1-x0, y1-y0
+	for _, e := range [4][2]float64{
+		{-dx, x0 - minX}, {dx, maxX - x0},
+		{-dy, y0 - minY}, {dy, maxY - y0},
+	} {
+		p, q := e[0], e[1]
+		if p == 0 {
+			if q < 0 {
+				return 0, 0, false
+			}
+			continue
+		}
+		t := q / p
+		if p < 0 {
+			if t > t1 {
+				return 0, 0, false
+			}
+			if t > t0 {
+				t0 = t
+			}
+		} else {
+			if t < t0 {
+				return 0, 0, false
+			}
+			if t < t1 {
+				t1 = t
+			}
+		}
+	}
+	return t0, t1, true
+}
+
+// clipLine is like clipT, but returns the clipped end points.
+func clipLine(x0, y0, x1, y1, minX, minY, maxX, maxY float64) (float64, float64, float64, float64, bool) {
+	t0, t1, ok := clipT(x0, y0, x1, y1, minX, minY, maxX, maxY)
+	if !ok {
+		return 0, 0, 0, 0, false
+	}
+	dx, dy := x1-x0, y1-y0
+	return x0 + t0*dx, y0 + t0*dy, x0 + t1*dx, y0 + t1*dy, true
+}
+
+// Line draws a line from x0/y0 to x1/y1 (inclusive). The pixels are the
+// same as with Bresenham's algorithm: one per step along the longer axis,
+// with the other coordinate rounded.
+func Line(dst draw.Image, x0, y0, x1, y1 int, c color.Color) {
+	dx, dy := x1-x0, y1-y0
+	n := abs(dx)
+	if abs(dy) > n {
+		n = abs(dy)
+	}
+	if n == 0 {
+		Set(dst, x0, y0, c)
+		return
+	}
+
+	// only step through the pixels inside of the image, but always from
+	// the original end points, so that clipping doesn't move pixels
+	b := dst.Bounds()
+	t0, t1, ok := clipT(float64(x0), float64(y0), float64(x1), float64(y1),
+		float64(b.Min.X)-1, float64(b.Min.Y)-1, float64(b.Max.X), float64(b.Max.Y))
+	if !ok {
+		return
+	}
+	i0, i1 := int(math.Floor(t0*float64(n))), int(math.Ceil(t1*float64(n)))
+	for i := i0; i <= i1; i++ {
+		Set(dst, x0+roundDiv(i*dx, n), y0+roundDiv(i*dy, n), c)
+	}
+}
+
+// roundDiv returns a/b rounded to the nearest integer, with halves rounded
+// away from zero. b must be positive.
+func roundDiv(a, b int) int {
+	if a < 0 {
+		return -((-a*2 + b) / (2 * b))
+	}
+	return (a*2 + b) / (2 * b)
+}
+
+// LineAA draws an anti-aliased line from x0/y0 to x1/y1 with Xiaolin Wu's
+// algorithm. Pixel centers are at integer coordinates.
+func LineAA(dst draw.Image, x0, y0, x1, y1 float64, c color.Color) {
+	b := dst.Bounds()
+	x0, y0, x1, y1, ok := clipLine(x0, y0, x1, y1,
+		float64(b.Min.X)-1, float64(b.Min.Y)-1, float64(b.Max.X), float64(b.Max.Y))
+	if !ok {
+		return
+	}
+
+	steep := math.Abs(y1-y0) > math.Abs(x1-x0)
+	if steep {
+		x0, y0 = y0, x0
+		x1, y1 = y1, x1
+	}
+	if x0 > x1 {
+		x0, x1 = x1, x0
+		y0, y1 = y1, y0
+	}
+	plot := func(x, y int, a float64) {
+		if steep {
+			x, y = y, x
+		}
+		Blend(dst, x, y, c, a)
+	}
+	gradient := 1.0
+	if dx := x1 - x0; dx != 0 {
+		gradient = (y1 - y0) / dx
+	}
+
+	// end points are weighted by their horizontal coverage
+	xs, xe := math.Round(x0), math.Round(x1)
+	for x := xs; x <= xe; x++ {
+		cov := 1.0
+		if x == xs {
+			cov = 1 - (x0 + 0.5 - xs)
+		}
+		if x == xe {
+			cov = x1 + 0.5 - xe
+		}
+		if xs == xe {
+			cov = x1 - x0
+			if cov < 0.5 {
+				cov = 0.5
+			}
+		}
+		y := y0 + gradient*(x-x0)
+		fy := math.Floor(y)
+		plot(int(x), int(fy), (1-(y-fy))*cov)
+		plot(int(x), int(fy)+1, (y-fy)*cov)
+	}
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
@@ -0,0 +1,144 @@
+package gfx
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+)
+
+var (
+	red  = color.RGBA{255, 0, 0, 255}
+	blue = color.RGBA{0, 0, 255, 128}
+)
+
+// clipped is the part of the test canvas that is drawn to. Everything
+// around it must stay untouched.
+var clipped = image.Rect(10, 10, 30, 30)
+
+func newCanvas() *image.RGBA {
+	return image.NewRGBA(image.Rect(-60, -60, 100, 100))
+}
+
+var shapes = []struct {
+	name string
+	draw func(dst draw.Image, x, y int)
+}{
+	{"FillRect", func(dst draw.Image, x, y int) { FillRect(dst, image.Rect(x-5, y-4, x+6, y+5), red) }},
+	{"Rect", func(dst draw.Image, x, y int) { Rect(dst, image.Rect(x-5, y-4, x+6, y+5), red) }},
+	{"LineH", func(dst draw.Image, x, y int) { Line(dst, x-7, y, x+7, y, red) }},
+	{"LineV", func(dst draw.Image, x, y int) { Line(dst, x, y-7, x, y+7, red) }},
+	{"LineDiag", func(dst draw.Image, x, y int) { Line(dst, x-6, y-6, x+6, y+6, red) }},
+	{"LineSlope", func(dst draw.Image, x, y int) { Line(dst, x-7, y-2, x+6, y+4, red) }},
+	{"LineAA", func(dst draw.Image, x, y int) {
+		LineAA(dst, float64(x-6), float64(y-6), float64(x+6), float64(y+6), red)
+	}},
+	{"Circle", func(dst draw.Image, x, y int) { Circle(dst, x, y, 5, red) }},
+	{"FillCircle", func(dst draw.Image, x, y int) { FillCircle(dst, x, y, 5, red) }},
+	{"Ellipse", func(dst draw.Image, x, y int) { Ellipse(dst, x, y, 7, 3, red) }},
+	{"FillEllipse", func(dst draw.Image, x, y int) { FillEllipse(dst, x, y, 7, 3, red) }},
+	{"Polygon", func(dst draw.Image, x, y int) {
+		Polygon(dst, []image.Point{{x, y - 6}, {x + 6, y + 5}, {x - 6, y + 5}}, red)
+	}},
+	{"FillPolygon", func(dst draw.Image, x, y int) {
+		FillPolygon(dst, []image.Point{{x, y - 6}, {x + 6, y + 5}, {x - 6, y + 5}}, red)
+	}},
+	{"LinearGradient", func(dst draw.Image, x, y int) {
+		LinearGradient(dst, image.Rect(x-6, y-6, x+6, y+6), image.Pt(x-6, y), image.Pt(x+6, y),
+			Gradient{{0, red}, {1, color.RGBA{0, 0, 255, 255}}})
+	}},
+	{"RadialGradient", func(dst draw.Image, x, y int) {
+		RadialGradient(dst, image.Rect(x-6, y-6, x+6, y+6), image.Pt(x, y), 6,
+			Gradient{{0, red}, {1, color.RGBA{0, 0, 255, 255}}})
+	}},
+	{"Blit", func(dst draw.Image, x, y int) {
+		sprite := image.NewRGBA(image.Rect(0, 0, 9, 7))
+		FillCircle(sprite, 4, 3, 3, blue)
+		Blit(dst, image.Pt(x-4, y-3), sprite, 0.75)
+	}},
+}
+
+func TestClipping(t *testing.T) {
+	mid := clipped.Min.Add(clipped.Size().Div(2))
+	positions := []struct {
+		name string
+		p    image.Point
+	}{
+		{"inside", mid},
+		{"left", image.Pt(clipped.Min.X, mid.Y)},
+		{"right", image.Pt(clipped.Max.X-1, mid.Y)},
+		{"top", image.Pt(mid.X, clipped.Min.Y)},
+		{"bottom", image.Pt(mid.X, clipped.Max.Y-1)},
+		{"top left", clipped.Min},
+		{"bottom right", clipped.Max.Sub(image.Pt(1, 1))},
+		{"outside", image.Pt(-40, 80)},
+	}
+	for _, s := range shapes {
+		for _, pos := range positions {
+			// draw once without clipping for the expected result
+			want := newCanvas()
+			s.draw(want, pos.p.X, pos.p.Y)
+
+			got := newCanvas()
+			s.draw(got.SubImage(clipped).(*image.RGBA), pos.p.X, pos.p.Y)
+
+			b := got.Bounds()
+			for y := b.Min.Y; y < b.Max.Y; y++ {
+				for x := b.Min.X; x < b.Max.X; x++ {
+					g := got.RGBAAt(x, y)
+					p := image.Pt(x, y)
+					if !p.In(clipped) {
+						if g != (color.RGBA{}) {
+							t.Errorf("%s %s: pixel %v outside of the clip rectangle is %v", s.name, pos.name, p, g)
+						}
+					} else if w := want.RGBAAt(x, y); g != w {
+						t.Errorf("%s %s: pixel %v is %v, want %v", s.name, pos.name, p, g, w)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFloodFillClipping(t *testing.T) {
+	for _, r := range []image.Rectangle{
+		image.Rect(5, 15, 20, 25),  // left
+		image.Rect(20, 15, 35, 25), // right
+		image.Rect(15, 5, 25, 20),  // top
+		image.Rect(15, 20, 25, 35), // bottom
+		image.Rect(0, 0, 40, 40),   // all around
+	} {
+		got := newCanvas()
+		dst := got.SubImage(clipped).(*image.RGBA)
+		Rect(dst, r, red)
+		in := r.Inset(1).Intersect(clipped)
+		FloodFill(dst, in.Min.X, in.Min.Y, blue)
+
+		b := got.Bounds()
+		for y := b.Min.Y; y < b.Max.Y; y++ {
+			for x := b.Min.X; x < b.Max.X; x++ {
+				p := image.Pt(x, y)
+				g := got.RGBAAt(x, y)
+				switch {
+				case !p.In(clipped):
+					if g != (color.RGBA{}) {
+						t.Errorf("%v: pixel %v outside of the clip rectangle is %v", r, p, g)
+					}
+				case p.In(in):
+					if g != blue {
+						t.Errorf("%v: pixel %v inside is %v, want %v", r, p, g, blue)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestFillPolygonFirstPoint(t *testing.T) {
+	// the apex is the first point and the only one on the top rows
+	dst := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	FillPolygon(dst, []image.Point{{5, 0}, {9, 9}, {1, 9}}, red)
+	if dst.RGBAAt(5, 1) != red {
+		t.Errorf("pixel below the apex is %v, want %v", dst.RGBAAt(5, 1), red)
+	}
+}
@@ -0,0 +1,129 @@
+package gfx
+
+import (
+	"bytes"
+	"flag"
+	"image"
+	"image/color"
+	"image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "write the golden images in testdata")
+
+// golden draws each primitive on a screen sized image and compares it with
+// testdata/<name>.png. Run the tests with -update after intended changes
+// and look at the new images.
+var golden = []struct {
+	name string
+	draw func(dst *image.RGBA)
+}{
+	{"line", func(dst *image.RGBA) {
+		for x := 0; x < 54; x += 6 {
+			Line(dst, 27, 18, x, 0, red)
+			Line(dst, 27, 18, x, 35, red)
+		}
+		Line(dst, -10, 40, 60, -5, blue)
+	}},
+	{"lineaa", func(dst *image.RGBA) {
+		for i := 0; i < 8; i++ {
+			LineAA(dst, 27, 18, 27+float64(i)*7.3-26, float64(i)*4.7-2, color.RGBA{255, 255, 255, 255})
+		}
+		LineAA(dst, 0.5, 35, 53, 30.25, red)
+	}},
+	{"rect", func(dst *image.RGBA) {
+		FillRect(dst, image.Rect(2, 2, 20, 14), red)
+		Rect(dst, image.Rect(10, 8, 40, 30), color.RGBA{0, 255, 0, 255})
+		FillRect(dst, image.Rect(44, 20, 60, 40), blue)
+	}},
+	{"circle", func(dst *image.RGBA) {
+		Circle(dst, 12, 12, 10, red)
+		FillCircle(dst, 40, 20, 8, color.RGBA{0, 255, 0, 255})
+		Ellipse(dst, 27, 30, 20, 4, blue)
+		FillEllipse(dst, 27, 30, 6, 3, color.RGBA{255, 255, 0, 255})
+	}},
+	{"polygon", func(dst *image.RGBA) {
+		star := []image.Point{{27, 1}, {32, 13}, {45, 13}, {35, 21}, {39, 34}, {27, 26}, {15, 34}, {19, 21}, {9, 13}, {22, 13}}
+		FillPolygon(dst, star, red)
+		Polygon(dst, star, color.RGBA{255, 255, 0, 255})
+		FillPolygon(dst, []image.Point{{0, 0}, {8, 0}, {0, 8}}, blue)
+	}},
+	{"floodfill", func(dst *image.RGBA) {
+		Circle(dst, 20, 18, 12, red)
+		Rect(dst, image.Rect(30, 5, 50, 30), red)
+		FloodFill(dst, 20, 18, color.RGBA{0, 255, 0, 255})
+		FloodFill(dst, 52, 0, blue)
+	}},
+	{"gradient", func(dst *image.RGBA) {
+		g := Gradient{{0, red}, {0.5, color.RGBA{255, 255, 0, 255}}, {1, color.RGBA{0, 0, 255, 255}}}
+		LinearGradient(dst, image.Rect(0, 0, 54, 18), image.Pt(0, 0), image.Pt(53, 17), g)
+		RadialGradient(dst, image.Rect(0, 18, 54, 36), image.Pt(27, 27), 20, g)
+	}},
+	{"blit", func(dst *image.RGBA) {
+		FillRect(dst, image.Rect(0, 0, 27, 36), color.RGBA{0, 80, 0, 255})
+		sprite := image.NewRGBA(image.Rect(0, 0, 16, 16))
+		FillCircle(sprite, 8, 8, 7, blue)
+		FillRect(sprite, image.Rect(6, 6, 10, 10), red)
+		Blit(dst, image.Pt(4, 4), sprite, 1)
+		Blit(dst, image.Pt(20, 10), sprite, 0.5)
+		Blit(dst, image.Pt(44, 26), sprite, 1)
+	}},
+}
+
+func TestGolden(t *testing.T) {
+	for _, g := range golden {
+		dst := image.NewRGBA(image.Rect(0, 0, 54, 36))
+		g.draw(dst)
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, dst); err != nil {
+			t.Fatal(err)
+		}
+		fname := filepath.Join("testdata", g.name+".png")
+		if *update {
+			if err := ioutil.WriteFile(fname, buf.Bytes(), 0644); err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+		want, err := readPNG(fname)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// compare after a round trip, as PNG stores translucent pixels
+		// without premultiplied alpha
+		got, err := png.Decode(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !sameImage(got, want) {
+			t.Errorf("%s differs from %s, run with -update to write it", g.name, fname)
+		}
+	}
+}
+
+func readPNG(fname string) (image.Image, error) {
+	f, err := os.Open(fname)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return png.Decode(f)
+}
+
+func sameImage(a, b image.Image) bool {
+	if !a.Bounds().Eq(b.Bounds()) {
+		return false
+	}
+	r := a.Bounds()
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			if color.NRGBA64Model.Convert(a.At(x, y)) != color.NRGBA64Model.Convert(b.At(x, y)) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,71 @@
+package gfx
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/ktt-ol/go-insta/colors"
+)
+
+// Stop is a color at a position (0-1) of a gradient.
+type Stop struct {
+	Offset float64
+	Color  color.RGBA
+}
+
+// Gradient is a list of color stops, sorted by offset.
+type Gradient []Stop
+
+// At returns the color at position t, interpolated in linear light.
+// Positions outside of the first and last stop return the color of that
+// stop.
+func (g Gradient) At(t float64) color.RGBA {
+	if len(g) == 0 {
+		return color.RGBA{}
+	}
+	if t <= g[0].Offset {
+		return g[0].Color
+	}
+	for i := 1; i < len(g); i++ {
+		if t < g[i].Offset {
+			a, b := g[i-1], g[i]
+			f := (t - a.Offset) / (b.Offset - a.Offset)
+			return colors.Lerp(a.Color, b.Color, f)
+		}
+	}
+	return g[len(g)-1].Color
+}
+
+// LinearGradient fills r with g. The gradient runs from p0 (offset 0) to
+// p1 (offset 1).
+func LinearGradient(dst draw.Image, r image.Rectangle, p0, p1 image.Point, g Gradient) {
+	r = r.Intersect(dst.Bounds())
+	dx, dy := float64(p1.X-p0.X), float64(p1.Y-p0.Y)
+	l2 := dx*dx + dy*dy
+	if l2 == 0 {
+		l2 = 1
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			t := (float64(x-p0.X)*dx + float64(y-p0.Y)*dy) / l2
+			Blend(dst, x, y, g.At(t), 1)
+		}
+	}
+}
+
+// RadialGradient fills r with g. The gradient runs from c (offset 0) to
+// the circle with the given radius (offset 1).
+func RadialGradient(dst draw.Image, r image.Rectangle, c image.Point, radius float64, g Gradient) {
+	r = r.Intersect(dst.Bounds())
+	if radius <= 0 {
+		radius = 1
+	}
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			t := math.Hypot(float64(x-c.X), float64(y-c.Y)) / radius
+			Blend(dst, x, y, g.At(t), 1)
+		}
+	}
+}
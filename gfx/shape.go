@@ -0,0 +1,172 @@
+package gfx
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"sort"
+)
+
+// ellipse calls plot for all points of the first quadrant of an ellipse
+// with the radii rx and ry (midpoint ellipse algorithm).
+func ellipse(rx, ry int, plot func(x, y int)) {
+	if rx < 0 || ry < 0 {
+		return
+	}
+	rx2, ry2 := float64(rx*rx), float64(ry*ry)
+	x, y := 0, ry
+	px, py := 0.0, 2*rx2*float64(y)
+
+	p := ry2 - rx2*float64(ry) + rx2/4
+	for px < py {
+		plot(x, y)
+		x++
+		px += 2 * ry2
+		if p < 0 {
+			p += ry2 + px
+		} else {
+			y--
+			py -= 2 * rx2
+			p += ry2 + px - py
+		}
+	}
+
+	fx, fy := float64(x)+0.5, float64(y-1)
+	p = ry2*fx*fx + rx2*fy*fy - rx2*ry2
+	for y >= 0 {
+		plot(x, y)
+		y--
+		py -= 2 * rx2
+		if p > 0 {
+			p += rx2 - py
+		} else {
+			x++
+			px += 2 * ry2
+			p += rx2 - py + px
+		}
+	}
+}
+
+// Ellipse draws the outline of an ellipse around cx/cy.
+func Ellipse(dst draw.Image, cx, cy, rx, ry int, c color.Color) {
+	ellipse(rx, ry, func(x, y int) {
+		Set(dst, cx+x, cy+y, c)
+		Set(dst, cx-x, cy+y, c)
+		Set(dst, cx+x, cy-y, c)
+		Set(dst, cx-x, cy-y, c)
+	})
+}
+
+// FillEllipse fills an ellipse around cx/cy.
+func FillEllipse(dst draw.Image, cx, cy, rx, ry int, c color.Color) {
+	ellipse(rx, ry, func(x, y int) {
+		FillRect(dst, image.Rect(cx-x, cy+y, cx+x+1, cy+y+1), c)
+		FillRect(dst, image.Rect(cx-x, cy-y, cx+x+1, cy-y+1), c)
+	})
+}
+
+// Circle draws the outline of a circle around cx/cy.
+func Circle(dst draw.Image, cx, cy, r int, c color.Color) {
+	Ellipse(dst, cx, cy, r, r, c)
+}
+
+// FillCircle fills a circle around cx/cy.
+func FillCircle(dst draw.Image, cx, cy, r int, c color.Color) {
+	FillEllipse(dst, cx, cy, r, r, c)
+}
+
+// Polygon draws the closed outline through all points.
+func Polygon(dst draw.Image, pts []image.Point, c color.Color) {
+	for i, p := range pts {
+		q := pts[(i+1)%len(pts)]
+		Line(dst, p.X, p.Y, q.X, q.Y, c)
+	}
+}
+
+// FillPolygon fills the polygon with the even-odd rule. A pixel is filled
+// if its center is inside of the polygon.
+func FillPolygon(dst draw.Image, pts []image.Point, c color.Color) {
+	if len(pts) < 3 {
+		return
+	}
+	bounds := image.Rectangle{Min: pts[0], Max: pts[0].Add(image.Pt(1, 1))}
+	for _, p := range pts[1:] {
+		bounds = bounds.Union(image.Rectangle{Min: p, Max: p.Add(image.Pt(1, 1))})
+	}
+	bounds = bounds.Intersect(dst.Bounds())
+
+	var xs []float64
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		yc := float64(y) + 0.5
+		xs = xs[:0]
+		for i, p := range pts {
+			q := pts[(i+1)%len(pts)]
+			y0, y1 := float64(p.Y), float64(q.Y)
+			if (y0 <= yc && yc < y1) || (y1 <= yc && yc < y0) {
+				t := (yc - y0) / (y1 - y0)
+				xs = append(xs, float64(p.X)+t*float64(q.X-p.X))
+			}
+		}
+		sort.Float64s(xs)
+		for i := 0; i+1 < len(xs); i += 2 {
+			x0 := int(math.Ceil(xs[i] - 0.5))
+			x1 := int(math.Ceil(xs[i+1] - 0.5))
+			FillRect(dst, image.Rect(x0, y, x1, y+1), c)
+		}
+	}
+}
+
+func sameColor(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
+
+// FloodFill replaces the 4-connected area of the same color as the pixel
+// at x/y with c.
+func FloodFill(dst draw.Image, x, y int, c color.Color) {
+	b := dst.Bounds()
+	if !image.Pt(x, y).In(b) {
+		return
+	}
+	target := dst.At(x, y)
+	// compare with the color as stored by dst
+	probe := dst.ColorModel().Convert(c)
+	if sameColor(target, probe) {
+		return
+	}
+
+	stack := []image.Point{{x, y}}
+	for len(stack) > 0 {
+		p := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if !sameColor(dst.At(p.X, p.Y), target) {
+			continue
+		}
+		// fill the span left and right of p
+		l, r := p.X, p.X
+		for l > b.Min.X && sameColor(dst.At(l-1, p.Y), target) {
+			l--
+		}
+		for r < b.Max.X-1 && sameColor(dst.At(r+1, p.Y), target) {
+			r++
+		}
+		for x := l; x <= r; x++ {
+			dst.Set(x, p.Y, c)
+		}
+		for _, ny := range []int{p.Y - 1, p.Y + 1} {
+			if ny < b.Min.Y || ny >= b.Max.Y {
+				continue
+			}
+			inSpan := false
+			for x := l; x <= r; x++ {
+				match := sameColor(dst.At(x, ny), target)
+				if match && !inSpan {
+					stack = append(stack, image.Pt(x, ny))
+				}
+				inSpan = match
+			}
+		}
+	}
+}
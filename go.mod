@@ -3,7 +3,6 @@ module github.com/ktt-ol/go-insta
 go 1.18
 
 require (
-	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
 	github.com/simulatedsimian/joystick v1.0.1
 	github.com/tarm/serial v0.0.0-20150317063745-e3f4c97bb713
 	golang.org/x/image v0.0.0-20220412021310-99f80d0ecbab
@@ -0,0 +1,209 @@
+package insta
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+
+	"github.com/ktt-ol/go-insta/colors"
+)
+
+// HDR is a frame buffer with linear light float32 RGB values. Values are
+// not limited to 0-1, light adds up till the frame is tone mapped to a
+// Screen with ToScreen.
+type HDR struct {
+	Pix  []float32
+	W, H int
+}
+
+// NewHDR returns a black frame with the size w x h.
+func NewHDR(w, h int) *HDR {
+	return &HDR{Pix: make([]float32, w*h*3), W: w, H: h}
+}
+
+// HDRFromScreen returns a frame with the linear values of s.
+func HDRFromScreen(s *Screen) *HDR {
+	f := NewHDR(ScreenWidth, ScreenHeight)
+	for i, v := range s.Pix {
+		f.Pix[i] = colors.ToLinear(v)
+	}
+	return f
+}
+
+// HDRFromImage returns a frame with the linear values of img.
+func HDRFromImage(img image.Image) *HDR {
+	b := img.Bounds()
+	f := NewHDR(b.Dx(), b.Dy())
+	for y := 0; y < f.H; y++ {
+		for x := 0; x < f.W; x++ {
+			f.Set(x, y, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+	return f
+}
+
+func (f *HDR) offset(x, y int) (int, bool) {
+	if x < 0 || y < 0 || x >= f.W || y >= f.H {
+		return 0, false
+	}
+	return (y*f.W + x) * 3, true
+}
+
+// Clear sets all pixels to black.
+func (f *HDR) Clear() {
+	for i := range f.Pix {
+		f.Pix[i] = 0
+	}
+}
+
+// Scale multiplies all pixels with v, e.g. to fade out trails.
+func (f *HDR) Scale(v float32) {
+	for i := range f.Pix {
+		f.Pix[i] *= v
+	}
+}
+
+// Add adds linear light to the pixel at x/y.
+func (f *HDR) Add(x, y int, r, g, b float32) {
+	o, ok := f.offset(x, y)
+	if !ok {
+		return
+	}
+	f.Pix[o] += r
+	f.Pix[o+1] += g
+	f.Pix[o+2] += b
+}
+
+// AddColor adds the sRGB color c with the intensity v to the pixel at x/y.
+// v can be larger than 1 for bright lights.
+func (f *HDR) AddColor(x, y int, c color.Color, v float32) {
+	r, g, b := colors.Linear(c)
+	f.Add(x, y, r*v, g*v, b*v)
+}
+
+// AddFrame adds all pixels of src, which needs to be of the same size.
+func (f *HDR) AddFrame(src *HDR) {
+	for i, v := range src.Pix {
+		f.Pix[i] += v
+	}
+}
+
+// Sample returns the bilinear interpolated value at x/y. Pixel centers are
+// at +0.5, positions outside of the frame are clamped to the border.
+func (f *HDR) Sample(x, y float64) (r, g, b float32) {
+	x, y = x-0.5, y-0.5
+	x0, y0 := int(math.Floor(x)), int(math.Floor(y))
+	fx, fy := float32(x-float64(x0)), float32(y-float64(y0))
+	clamp := func(v, max int) int {
+		if v < 0 {
+			return 0
+		} else if v >= max {
+			return max - 1
+		}
+		return v
+	}
+	xs := [2]int{clamp(x0, f.W), clamp(x0+1, f.W)}
+	ys := [2]int{clamp(y0, f.H), clamp(y0+1, f.H)}
+	ws := [4]float32{(1 - fx) * (1 - fy), fx * (1 - fy), (1 - fx) * fy, fx * fy}
+	for i, w := range ws {
+		o := (ys[i/2]*f.W + xs[i%2]) * 3
+		r += f.Pix[o] * w
+		g += f.Pix[o+1] * w
+		b += f.Pix[o+2] * w
+	}
+	return r, g, b
+}
+
+// Set replaces the pixel at x/y with the sRGB color c. Together with At,
+// Bounds and ColorModel it makes HDR a draw.Image, so that all drawing
+// functions can render into it.
+func (f *HDR) Set(x, y int, c color.Color) {
+	o, ok := f.offset(x, y)
+	if !ok {
+		return
+	}
+	f.Pix[o], f.Pix[o+1], f.Pix[o+2] = colors.Linear(c)
+}
+
+// At returns the pixel at x/y clamped to 0-1 as sRGB color.
+func (f *HDR) At(x, y int) color.Color {
+	o, ok := f.offset(x, y)
+	if !ok {
+		return color.RGBA{}
+	}
+	return colors.FromLinearRGB(f.Pix[o], f.Pix[o+1], f.Pix[o+2])
+}
+
+func (f *HDR) Bounds() image.Rectangle {
+	return image.Rect(0, 0, f.W, f.H)
+}
+
+func (f *HDR) ColorModel() color.Model {
+	return color.RGBAModel
+}
+
+// ToneMap maps a linear value of 0-inf to 0-1.
+type ToneMap func(v float32) float32
+
+// ToneClamp cuts values above 1.
+func ToneClamp(v float32) float32 {
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+// ToneReinhard compresses highlights with v/(1+v).
+func ToneReinhard(v float32) float32 {
+	return v / (1 + v)
+}
+
+// ToneACES is a fit of the ACES filmic curve, with more contrast than
+// Reinhard.
+func ToneACES(v float32) float32 {
+	const a, b, c, d, e = 2.51, 0.03, 2.43, 0.59, 0.14
+	v = (v * (a*v + b)) / (v*(c*v+d) + e)
+	if v > 1 {
+		return 1
+	}
+	return v
+}
+
+var toneMaps = map[string]ToneMap{
+	"clamp":    ToneClamp,
+	"reinhard": ToneReinhard,
+	"aces":     ToneACES,
+}
+
+// ParseToneMap returns the tone map with the name clamp, reinhard or aces.
+func ParseToneMap(s string) (ToneMap, error) {
+	if tm, ok := toneMaps[s]; ok {
+		return tm, nil
+	}
+	return nil, fmt.Errorf("unknown tone map %q, expected clamp, reinhard or aces", s)
+}
+
+// ToScreen multiplies all values with the exposure, tone maps them with tm
+// and draws them as sRGB into dst. This is the only point where the values
+// are quantized to 8 bit.
+func (f *HDR) ToScreen(dst draw.Image, exposure float32, tm ToneMap) {
+	if tm == nil {
+		tm = ToneClamp
+	}
+	b := dst.Bounds()
+	if s, ok := dst.(*Screen); ok && f.W == ScreenWidth && f.H == ScreenHeight {
+		for i, v := range f.Pix {
+			s.Pix[i] = colors.FromLinear(tm(v * exposure))
+		}
+		return
+	}
+	for y := 0; y < f.H && y < b.Dy(); y++ {
+		for x := 0; x < f.W && x < b.Dx(); x++ {
+			o := (y*f.W + x) * 3
+			dst.Set(b.Min.X+x, b.Min.Y+y, colors.FromLinearRGB(
+				tm(f.Pix[o]*exposure), tm(f.Pix[o+1]*exposure), tm(f.Pix[o+2]*exposure)))
+		}
+	}
+}
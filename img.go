@@ -6,96 +6,179 @@ import (
 	"image/gif"
 	_ "image/jpeg"
 	_ "image/png"
+	"io/ioutil"
 	"log"
-	"math/rand"
 	"os"
 	"path/filepath"
-	"strings"
+	"sync"
 	"time"
 
-	"github.com/nfnt/resize"
+	"github.com/ktt-ol/go-insta/anim"
+	"github.com/ktt-ol/go-insta/fit"
+	"github.com/ktt-ol/go-insta/media"
+	"github.com/ktt-ol/go-insta/resample"
+	"github.com/ktt-ol/go-insta/timing"
 )
 
-func ShowImage(c Client, fname string) {
-	if strings.HasSuffix(fname, ".gif") {
-		showGif(c, fname, 0)
-		return
-	}
+// decodeImage returns the image in fname, the first frame for gifs.
+func decodeImage(fname string) (image.Image, error) {
 	r, err := os.Open(fname)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
 	defer r.Close()
-
 	img, _, err := image.Decode(r)
+	return img, err
+}
+
+// ShowImage shows the image in fname, fitted to the screen. Gifs and
+// animated pngs are played once.
+func ShowImage(c Client, fname string, fo fit.Options) {
+	clip, err := loadClip(fname, fo)
 	if err != nil {
 		log.Fatal(err)
 	}
-
-	img = resize.Resize(ScreenWidth, ScreenHeight, img, resize.Bilinear)
-
+	if len(clip.Frames) != 1 {
+		PlayClip(c, clip, 0)
+		return
+	}
 	scr := NewScreen()
-	bnds := image.Rect(0, 0, ScreenWidth, ScreenHeight)
-	draw.Draw(scr, bnds, img, image.ZP, draw.Over)
+	draw.Draw(scr, scr.Bounds(), clip.Frames[0].Image, image.ZP, draw.Src)
 
 	c.SetScreen(scr)
 }
 
-func showGif(c Client, fname string, d time.Duration) {
-	r, err := os.Open(fname)
+type clipKey struct {
+	fname string
+	fo    fit.Options
+}
+
+type cachedClip struct {
+	mod  time.Time
+	clip *anim.Clip
+}
+
+// maxCachedClips is the number of decoded clips kept in clipCache.
+const maxCachedClips = 4
+
+// clipCache caches the fitted clips of the last used images and gifs, till
+// the file changes. used is ordered from the least to the most recently
+// used key.
+var clipCache = struct {
+	sync.Mutex
+	m    map[clipKey]cachedClip
+	used []clipKey
+}{m: map[clipKey]cachedClip{}}
+
+// useClip marks k as the most recently used key and removes the least
+// recently used clips above maxCachedClips. clipCache must be locked.
+func useClip(k clipKey) {
+	for i, u := range clipCache.used {
+		if u == k {
+			clipCache.used = append(clipCache.used[:i], clipCache.used[i+1:]...)
+			break
+		}
+	}
+	clipCache.used = append(clipCache.used, k)
+	for len(clipCache.used) > maxCachedClips {
+		delete(clipCache.m, clipCache.used[0])
+		clipCache.used = clipCache.used[1:]
+	}
+}
+
+func loadClip(fname string, fo fit.Options) (*anim.Clip, error) {
+	fi, err := os.Stat(fname)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
-	defer r.Close()
-	g, err := gif.DecodeAll(r)
+	k := clipKey{fname, fo}
+	clipCache.Lock()
+	cached, ok := clipCache.m[k]
+	if ok && cached.mod.Equal(fi.ModTime()) {
+		useClip(k)
+		clipCache.Unlock()
+		return cached.clip, nil
+	}
+	clipCache.Unlock()
+
+	data, err := ioutil.ReadFile(fname)
 	if err != nil {
-		log.Fatal(err)
-		return
+		return nil, err
 	}
-	start := time.Now()
-	bnds := image.Rect(0, 0, ScreenWidth, ScreenHeight)
+	clip, err := media.Decode(data)
+	if err != nil {
+		return nil, err
+	}
+	clip = clip.Fit(ScreenWidth, ScreenHeight, fo)
+
+	clipCache.Lock()
+	clipCache.m[k] = cachedClip{fi.ModTime(), clip}
+	useClip(k)
+	clipCache.Unlock()
+	return clip, nil
+}
+
+// PlayGif plays the frames of g fitted to the screen, see PlayClip.
+func PlayGif(c Client, g *gif.GIF, d time.Duration, fo fit.Options) {
+	PlayClip(c, anim.FromGIF(g).Fit(ScreenWidth, ScreenHeight, fo), d)
+}
+
+// PlayClip plays the screen sized frames of clip for the duration d, or a
+// single time if d is 0. The clip repeats as often as its loop count
+// allows, afterwards the last frame stays till d passed.
+func PlayClip(c Client, clip *anim.Clip, d time.Duration) {
+	end := d
+	if end == 0 {
+		end = clip.Duration()
+	}
+	start := timing.Now()
+	last := -1
 	for {
-		scr := NewScreen()
-		for i := range g.Image {
-			img := resize.Resize(ScreenWidth, ScreenHeight, g.Image[i], resize.Bilinear)
-			op := draw.Over
-			if g.Disposal[i] == gif.DisposalBackground {
-				op = draw.Src
-			}
-			draw.Draw(scr, bnds, img, image.ZP, op)
+		el := timing.Since(start)
+		if el >= end {
+			return
+		}
+		i, left := clip.FrameAt(el)
+		if i != last && i < len(clip.Frames) {
+			scr := NewScreen()
+			draw.Draw(scr, scr.Bounds(), clip.Frames[i].Image, image.ZP, draw.Src)
 			c.SetScreenImmediate(scr)
-			time.Sleep(time.Duration(g.Delay[i]) * time.Millisecond)
+			last = i
 		}
-		if d == 0 {
-			return
-		} else if time.Since(start) > d {
-			return
+		if left < 0 || el+left > end {
+			left = end - el
 		}
+		timing.Sleep(left)
 	}
 }
 
-func RandomGif(c Client, dir string, d time.Duration) {
-	gifs, _ := filepath.Glob(filepath.Join(dir, "*.gif"))
-	if len(gifs) == 0 {
-		return
+// PlayMedia plays the image or gif fname for the duration d. The frames
+// are read from the cache mc, or without cache decoded and fitted with fo
+// or the .fit file of the directory, see media.DirFit.
+func PlayMedia(c Client, mc *media.Cache, fname string, d time.Duration, fo fit.Options) {
+	var clip *anim.Clip
+	var err error
+	if mc != nil {
+		clip, err = mc.Clip(fname)
+	} else {
+		clip, err = loadClip(fname, media.DirFit(filepath.Dir(fname), fo))
 	}
-	i := rand.Intn(len(gifs))
-	showGif(c, gifs[i], d)
-}
-
-func ScrollImage(c Client, fname string) {
-	r, err := os.Open(fname)
 	if err != nil {
-		log.Fatal(err)
+		log.Printf("%s: %s", fname, err)
+		return
 	}
-	defer r.Close()
+	PlayClip(c, clip, d)
+}
 
-	img, _, err := image.Decode(r)
+// ScrollImage scrolls the image in fname once from right to left. The
+// image is scaled to the screen height with the filter f.
+func ScrollImage(c Client, fname string, f resample.Filter) {
+	img, err := decodeImage(fname)
 	if err != nil {
 		log.Fatal(err)
 	}
 
-	img = resize.Resize(0, ScreenHeight, img, resize.Bilinear)
+	img = resample.Resize(img, 0, ScreenHeight, f)
 
 	steps := img.Bounds().Dx() + ScreenWidth + 1
 	for i := 0; i < steps; i++ {
@@ -106,3 +189,24 @@ func ScrollImage(c Client, fname string) {
 		c.SetScreen(scr)
 	}
 }
+
+// PanImage scrolls the image in fname for the duration d with the velocity
+// vx/vy in pixels per second. The image is scaled to the screen height (or
+// width for vertical scrolling) with the filter f and repeated.
+func PanImage(c Client, fname string, vx, vy float64, d time.Duration, f resample.Filter) {
+	img, err := decodeImage(fname)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if vy != 0 && vx == 0 {
+		img = resample.Resize(img, ScreenWidth, 0, f)
+	} else {
+		img = resample.Resize(img, 0, ScreenHeight, f)
+	}
+	v := &Viewport{Canvas: NewCanvasFromImage(img)}
+	v.Camera = NewCamera(ScreenWidth/2, ScreenHeight/2)
+	v.Camera.Wrap = true
+	v.Camera.VX, v.Camera.VY = vx, vy
+	RunMode(c, v, d)
+}
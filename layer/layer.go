@@ -0,0 +1,256 @@
+// Package layer composites several RGBA layers with opacity and blend modes
+// into a Screen.
+package layer
+
+import (
+	"fmt"
+	"image"
+	"sync"
+	"time"
+
+	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/timing"
+)
+
+type BlendMode int
+
+const (
+	BlendNormal BlendMode = iota
+	BlendAdd
+	BlendMultiply
+	BlendScreen
+)
+
+var blendModeNames = []string{"normal", "add", "multiply", "screen"}
+
+func (m BlendMode) String() string {
+	if int(m) < len(blendModeNames) {
+		return blendModeNames[m]
+	}
+	return fmt.Sprintf("BlendMode(%d)", int(m))
+}
+
+// ParseBlendMode returns the mode for the name as returned by
+// BlendMode.String.
+func ParseBlendMode(s string) (BlendMode, error) {
+	for i, n := range blendModeNames {
+		if n == s {
+			return BlendMode(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown blend mode %q", s)
+}
+
+// Layer is an image with alpha that is drawn over the layers below.
+type Layer struct {
+	*image.RGBA
+	Name    string
+	Opacity float64
+	Mode    BlendMode
+	Visible bool
+	// Update is called before each frame with the time since the
+	// compositor was created, e.g. to animate overlays.
+	Update func(l *Layer, el time.Duration)
+}
+
+// Clear makes the whole layer transparent.
+func (l *Layer) Clear() {
+	for i := range l.Pix {
+		l.Pix[i] = 0
+	}
+}
+
+// SetScreen copies s into the layer. The layer is opaque afterwards.
+func (l *Layer) SetScreen(s *insta.Screen) {
+	for i, j := 0, 0; i < len(s.Pix); i, j = i+insta.PixelStride, j+4 {
+		l.Pix[j] = s.Pix[i]
+		l.Pix[j+1] = s.Pix[i+1]
+		l.Pix[j+2] = s.Pix[i+2]
+		l.Pix[j+3] = 255
+	}
+}
+
+// Compositor owns a stack of layers. The first layer is the bottom layer.
+type Compositor struct {
+	mu     sync.Mutex
+	layers []*Layer
+	out    insta.Client
+	start  time.Time
+}
+
+// NewCompositor returns a compositor that sends flattened frames to out.
+func NewCompositor(out insta.Client) *Compositor {
+	return &Compositor{
+		out:   out,
+		start: timing.Now(),
+	}
+}
+
+// Add adds a new transparent layer on top.
+func (c *Compositor) Add(name string) *Layer {
+	l := &Layer{
+		RGBA:    image.NewRGBA(image.Rect(0, 0, insta.ScreenWidth, insta.ScreenHeight)),
+		Name:    name,
+		Opacity: 1,
+		Visible: true,
+	}
+	c.mu.Lock()
+	c.layers = append(c.layers, l)
+	c.mu.Unlock()
+	return l
+}
+
+// Layer returns the layer with the name or nil.
+func (c *Compositor) Layer(name string) *Layer {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, l := range c.layers {
+		if l.Name == name {
+			return l
+		}
+	}
+	return nil
+}
+
+// Remove removes the layer with the name.
+func (c *Compositor) Remove(name string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, l := range c.layers {
+		if l.Name == name {
+			c.layers = append(c.layers[:i], c.layers[i+1:]...)
+			return
+		}
+	}
+}
+
+// Do runs fn while no frame is flattened, to modify layers from other
+// goroutines.
+func (c *Compositor) Do(fn func()) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fn()
+}
+
+// Flatten updates all layers and draws them into dst.
+func (c *Compositor) Flatten(dst *insta.Screen) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el := timing.Since(c.start)
+	for i := range dst.Pix {
+		dst.Pix[i] = 0
+	}
+	for _, l := range c.layers {
+		if l.Update != nil {
+			l.Update(l, el)
+		}
+		l.Composite(dst)
+	}
+}
+
+// Composite draws the layer with its opacity and blend mode over dst.
+// Hidden layers are skipped.
+func (l *Layer) Composite(dst *insta.Screen) {
+	if !l.Visible || l.Opacity <= 0 {
+		return
+	}
+	op := uint32(l.Opacity * 255)
+	if op > 255 {
+		op = 255
+	}
+	for i, j := 0, 0; i < len(dst.Pix); i, j = i+insta.PixelStride, j+4 {
+		a := uint32(l.Pix[j+3]) * op / 255
+		if a == 0 {
+			continue
+		}
+		for k := 0; k < 3; k++ {
+			d := uint32(dst.Pix[i+k])
+			// un-premultiply the layer color
+			s := uint32(l.Pix[j+k]) * 255 / uint32(l.Pix[j+3])
+			var b uint32
+			switch l.Mode {
+			case BlendAdd:
+				b = d + s
+				if b > 255 {
+					b = 255
+				}
+			case BlendMultiply:
+				b = d * s / 255
+			case BlendScreen:
+				b = 255 - (255-d)*(255-s)/255
+			default:
+				b = s
+			}
+			dst.Pix[i+k] = uint8((d*(255-a) + b*a) / 255)
+		}
+	}
+}
+
+// Client returns a client that draws each screen into l and sends the
+// flattened layers to the output client. While Run is active, the layers
+// are also flattened at the client FPS when no screen arrives, so that
+// animated layers keep moving over still images.
+func (c *Compositor) Client(l *Layer) insta.Client {
+	return &layerClient{c: c, l: l, fps: 50}
+}
+
+type layerClient struct {
+	c *Compositor
+	l *Layer
+	// guarded by c.mu
+	fps  int
+	last time.Time
+}
+
+func (lc *layerClient) render(s *insta.Screen) *insta.Screen {
+	lc.c.mu.Lock()
+	if s != nil {
+		lc.l.SetScreen(s)
+	}
+	lc.last = timing.Now()
+	lc.c.mu.Unlock()
+	frame := insta.NewScreen()
+	lc.c.Flatten(frame)
+	return frame
+}
+
+func (lc *layerClient) SetScreen(s *insta.Screen) {
+	lc.c.out.SetScreen(lc.render(s))
+}
+
+func (lc *layerClient) SetScreenImmediate(s *insta.Screen) {
+	lc.c.out.SetScreenImmediate(lc.render(s))
+}
+
+func (lc *layerClient) SetFPS(fps int) {
+	lc.c.mu.Lock()
+	if fps <= 0 {
+		fps = 50
+	}
+	lc.fps = fps
+	lc.c.mu.Unlock()
+	lc.c.out.SetFPS(fps)
+}
+
+func (lc *layerClient) Run() {
+	go lc.refresh()
+	lc.c.out.Run()
+}
+
+// refresh flattens the last screen again whenever no new screen arrived
+// within one frame.
+func (lc *layerClient) refresh() {
+	for {
+		lc.c.mu.Lock()
+		d := time.Second / time.Duration(lc.fps)
+		idle := timing.Since(lc.last)
+		lc.c.mu.Unlock()
+		if idle < d {
+			timing.Sleep(d - idle)
+			continue
+		}
+		lc.c.out.SetScreenImmediate(lc.render(nil))
+	}
+}
+
+func (lc *layerClient) SetAfterglow(v float64) { lc.c.out.SetAfterglow(v) }
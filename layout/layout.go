@@ -0,0 +1,94 @@
+// Package layout splits the screen into regions that run their own modes.
+package layout
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ktt-ol/go-insta"
+)
+
+// Region is a part of the screen that shows a mode.
+type Region struct {
+	Name string
+	Rect image.Rectangle
+	Mode insta.Mode
+}
+
+// Layout draws several modes side by side. It is a Mode itself, so layouts
+// can be nested.
+type Layout struct {
+	Regions []Region
+}
+
+// Add adds a region. Later regions are drawn over earlier regions if they
+// overlap.
+func (l *Layout) Add(name string, r image.Rectangle, m insta.Mode) {
+	l.Regions = append(l.Regions, Region{Name: name, Rect: r, Mode: m})
+}
+
+// Draw draws all regions into views of dst.
+func (l *Layout) Draw(dst draw.Image, el time.Duration) {
+	for _, r := range l.Regions {
+		r.Mode.Draw(insta.NewView(dst, r.Rect), el)
+	}
+}
+
+// Panels returns the rectangle of w x h panels, starting at panel x/y.
+func Panels(x, y, w, h int) image.Rectangle {
+	return image.Rect(x*insta.PanelWidth, y*insta.PanelHeight,
+		(x+w)*insta.PanelWidth, (y+h)*insta.PanelHeight)
+}
+
+// Parse parses a layout like "clock=p0,0,1,2 life=p1,0,2,2". Each region is
+// a mode name and x,y,width,height in pixels or, with the prefix p, in
+// panels. newMode returns the mode for a name and the size of its region.
+func Parse(spec string, newMode func(name string, w, h int) (insta.Mode, error)) (*Layout, error) {
+	l := &Layout{}
+	for _, field := range strings.Fields(spec) {
+		parts := strings.SplitN(field, "=", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid region %q, expected name=x,y,w,h", field)
+		}
+		r, err := parseRect(parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("invalid region %q: %v", field, err)
+		}
+		m, err := newMode(parts[0], r.Dx(), r.Dy())
+		if err != nil {
+			return nil, err
+		}
+		l.Add(parts[0], r, m)
+	}
+	if len(l.Regions) == 0 {
+		return nil, fmt.Errorf("empty layout")
+	}
+	return l, nil
+}
+
+func parseRect(s string) (image.Rectangle, error) {
+	panels := strings.HasPrefix(s, "p")
+	s = strings.TrimPrefix(s, "p")
+	var v [4]int
+	nums := strings.Split(s, ",")
+	if len(nums) != 4 {
+		return image.Rectangle{}, fmt.Errorf("expected x,y,w,h")
+	}
+	for i, n := range nums {
+		var err error
+		if v[i], err = strconv.Atoi(n); err != nil {
+			return image.Rectangle{}, err
+		}
+	}
+	if v[2] <= 0 || v[3] <= 0 {
+		return image.Rectangle{}, fmt.Errorf("empty region")
+	}
+	if panels {
+		return Panels(v[0], v[1], v[2], v[3]), nil
+	}
+	return image.Rect(v[0], v[1], v[0]+v[2], v[1]+v[3]), nil
+}
@@ -0,0 +1,162 @@
+// Package ledrec imports recordings of LED matrix software like Glediator
+// and Jinx!. The recordings are raw RGB frames without any header, the
+// size of the matrix and the frame rate need to be known.
+package ledrec
+
+import (
+	"fmt"
+	"image"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"github.com/ktt-ol/go-insta/anim"
+)
+
+// Format describes the frames of a recording.
+type Format struct {
+	W, H int
+	FPS  float64
+	// Sync frames start with the byte 1, like the Glediator protocol.
+	// Recordings starting with 1 and a matching size are detected as
+	// synced with SyncAuto.
+	Sync Sync
+	// Columns stores the pixels column by column instead of row by row.
+	Columns bool
+	// Serpentine reverses every second row (or column), like matrices
+	// wired in a zigzag.
+	Serpentine bool
+	// Order of the color channels, e.g. "grb" for WS2811 strips.
+	Order string
+}
+
+type Sync int
+
+const (
+	SyncAuto Sync = iota
+	SyncOn
+	SyncOff
+)
+
+// ParseFormat parses formats like "16x16@30 glediator serpentine grb". The
+// size is required, the frame rate defaults to 25. Options are glediator
+// (synced frames), jinx or raw (without sync), columns, serpentine and the
+// color order.
+func ParseFormat(s string) (Format, error) {
+	f := Format{FPS: 25, Order: "rgb"}
+	fields := strings.Fields(s)
+	if len(fields) == 0 {
+		return f, fmt.Errorf("missing recording size, expected WxH@FPS")
+	}
+	size := fields[0]
+	if i := strings.IndexByte(size, '@'); i >= 0 {
+		if _, err := fmt.Sscanf(size[i+1:], "%g", &f.FPS); err != nil || f.FPS <= 0 {
+			return f, fmt.Errorf("invalid frame rate in %q", size)
+		}
+		size = size[:i]
+	}
+	if _, err := fmt.Sscanf(size, "%dx%d", &f.W, &f.H); err != nil || f.W <= 0 || f.H <= 0 {
+		return f, fmt.Errorf("invalid recording size %q, expected WxH@FPS", size)
+	}
+	for _, o := range fields[1:] {
+		switch o {
+		case "glediator":
+			f.Sync = SyncOn
+		case "jinx", "raw":
+			f.Sync = SyncOff
+		case "columns":
+			f.Columns = true
+		case "serpentine", "zigzag":
+			f.Serpentine = true
+		default:
+			if !validOrder(o) {
+				return f, fmt.Errorf("unknown recording option %q", o)
+			}
+			f.Order = o
+		}
+	}
+	return f, nil
+}
+
+func validOrder(o string) bool {
+	return len(o) == 3 && strings.ContainsRune(o, 'r') && strings.ContainsRune(o, 'g') && strings.ContainsRune(o, 'b')
+}
+
+// Load reads the recording in fname.
+func Load(fname string, f Format) (*anim.Clip, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	c, err := Decode(data, f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	return c, nil
+}
+
+// Decode returns the frames of the recording in the size of the matrix. A
+// partial frame at the end is ignored.
+func Decode(data []byte, f Format) (*anim.Clip, error) {
+	size := f.W * f.H * 3
+	sync := f.Sync == SyncOn
+	if f.Sync == SyncAuto {
+		sync = len(data) > 0 && data[0] == 1 && len(data)%(size+1) == 0
+	}
+	frameSize := size
+	if sync {
+		frameSize++
+	}
+	if len(data) < frameSize {
+		return nil, fmt.Errorf("recording smaller than a single %dx%d frame", f.W, f.H)
+	}
+	order := f.Order
+	if order == "" {
+		order = "rgb"
+	} else if !validOrder(order) {
+		return nil, fmt.Errorf("invalid color order %q", order)
+	}
+	var channel [3]int
+	for i, c := range order {
+		channel[strings.IndexRune("rgb", c)] = i
+	}
+
+	delay := time.Duration(float64(time.Second) / f.FPS)
+	c := &anim.Clip{}
+	for off := 0; off+frameSize <= len(data); off += frameSize {
+		src := data[off : off+frameSize]
+		if sync {
+			if src[0] != 1 {
+				return nil, fmt.Errorf("missing frame sync at offset %d", off)
+			}
+			src = src[1:]
+		}
+		img := image.NewRGBA(image.Rect(0, 0, f.W, f.H))
+		for i := 0; i < f.W*f.H; i++ {
+			x, y := f.position(i)
+			o := img.PixOffset(x, y)
+			for ch := 0; ch < 3; ch++ {
+				img.Pix[o+ch] = src[i*3+channel[ch]]
+			}
+			img.Pix[o+3] = 255
+		}
+		c.Frames = append(c.Frames, anim.Frame{Image: img, Delay: delay})
+	}
+	return c, nil
+}
+
+// position returns the matrix position of the i-th pixel of a frame.
+func (f Format) position(i int) (x, y int) {
+	if f.Columns {
+		x, y = i/f.H, i%f.H
+		if f.Serpentine && x%2 == 1 {
+			y = f.H - 1 - y
+		}
+		return x, y
+	}
+	x, y = i%f.W, i/f.W
+	if f.Serpentine && y%2 == 1 {
+		x = f.W - 1 - x
+	}
+	return x, y
+}
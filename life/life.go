@@ -7,10 +7,11 @@ package life
 import (
 	"bytes"
 	"image/color"
+	"image/draw"
 	"math"
 	"math/rand"
 
-	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/theme"
 )
 
 type Cell struct {
@@ -203,17 +204,19 @@ func (l *Life) String() string {
 	return buf.String()
 }
 
-func (l *Life) UpdateScreen(s *insta.Screen) {
+func cellColor(c Cell, pal *theme.Palette) color.RGBA {
+	if !c.Alive {
+		return color.RGBA{0, 0, 0, 128}
+	}
+	return pal.Hue(float64(c.Hue))
+}
+
+func (l *Life) UpdateScreen(s draw.Image) {
 	f := l.Field()
-	for y := 0; y < insta.ScreenHeight; y++ {
-		for x := 0; x < insta.ScreenWidth; x++ {
-			c := f.Cell(x, y)
-			if c.Alive {
-				r, g, b := insta.HsvToRgb(float64(c.Hue), 0.7, 0.8)
-				s.Set(x, y, color.RGBA{uint8(r * 255), uint8(g * 255), uint8(b * 255), 128})
-			} else {
-				s.Set(x, y, color.RGBA{0, 0, 0, 128})
-			}
+	pal := theme.Current().Cycled()
+	for y := 0; y < l.h; y++ {
+		for x := 0; x < l.w; x++ {
+			s.Set(x, y, cellColor(f.Cell(x, y), pal))
 		}
 	}
 }
@@ -0,0 +1,69 @@
+package life
+
+import (
+	"image/draw"
+	"time"
+
+	"github.com/ktt-ol/go-insta/colors"
+	"github.com/ktt-ol/go-insta/theme"
+)
+
+// Mode runs Life as an insta.Mode. The field has the size of the target
+// and the cells fade between two steps.
+type Mode struct {
+	StepsPerSecond float64
+	// SpaceshipEvery adds a random spaceship in this interval.
+	SpaceshipEvery time.Duration
+
+	l         *Life
+	prev      *Field
+	steps     int
+	spaceship int
+	last      time.Duration
+}
+
+func NewMode() *Mode {
+	return &Mode{
+		StepsPerSecond: 10,
+		SpaceshipEvery: 2 * time.Second,
+	}
+}
+
+func (m *Mode) Draw(dst draw.Image, el time.Duration) {
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	// start a new field when the mode is played again from the start
+	if m.l == nil || m.l.w != w || m.l.h != h || el < m.last {
+		m.l = NewLife(w, h)
+		m.prev = NewField(w, h)
+		m.steps, m.spaceship = 0, 0
+	}
+	m.last = el
+
+	if m.SpaceshipEvery > 0 {
+		for n := int(el / m.SpaceshipEvery); m.spaceship < n; m.spaceship++ {
+			m.l.addRandomSpaceship()
+		}
+	}
+
+	pos := el.Seconds() * m.StepsPerSecond
+	for n := int(pos); m.steps < n; m.steps++ {
+		copyField(m.prev, m.l.a)
+		m.l.Step()
+	}
+	t := pos - float64(int(pos))
+
+	pal := theme.Current().Cycled()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			a := cellColor(m.prev.Cell(x, y), pal)
+			b := cellColor(m.l.a.Cell(x, y), pal)
+			dst.Set(x, y, colors.Lerp(a, b, t))
+		}
+	}
+}
+
+func copyField(dst, src *Field) {
+	for y := range src.s {
+		copy(dst.s[y], src.s[y])
+	}
+}
@@ -0,0 +1,300 @@
+// Package media converts images and gifs once to screen sized frames and
+// keeps them in a cache on disk, so that they can be played without
+// decoding and scaling.
+package media
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"image/draw"
+	_ "image/jpeg"
+	_ "image/png"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktt-ol/go-insta/anim"
+	"github.com/ktt-ol/go-insta/fit"
+)
+
+// Extensions are the file types of the media directories.
+var Extensions = []string{".gif", ".png", ".jpg", ".jpeg"}
+
+// Cache stores the converted media files in Dir, keyed by the hash of the
+// file content, the target size and the fit options. Renamed or copied
+// files are not converted again.
+type Cache struct {
+	Dir  string
+	W, H int
+	// Fit is used for directories without a .fit file.
+	Fit fit.Options
+
+	mu      sync.Mutex
+	entries map[string]entry
+	// loading are the files that are converted right now
+	loading map[string]*loadCall
+}
+
+type entry struct {
+	mod  time.Time
+	size int64
+	fo   fit.Options
+	key  string
+}
+
+// loadCall is a running load, others wait for it instead of converting the
+// same file again.
+type loadCall struct {
+	done chan struct{}
+	clip *anim.Clip
+	err  error
+}
+
+// NewCache returns a cache in dir for frames of the size w x h.
+func NewCache(dir string, w, h int, fo fit.Options) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	return &Cache{Dir: dir, W: w, H: h, Fit: fo, entries: map[string]entry{}, loading: map[string]*loadCall{}}, nil
+}
+
+// DirFit returns the fit options of dir. A file .fit in dir overrides fo
+// for all files of the directory, e.g. "cover" or "contain 000020".
+func DirFit(dir string, fo fit.Options) fit.Options {
+	fname := filepath.Join(dir, ".fit")
+	b, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return fo
+	}
+	dirFit, err := fit.Parse(string(b))
+	if err != nil {
+		log.Printf("%s: %s", fname, err)
+		return fo
+	}
+	return dirFit
+}
+
+// Watch scans the directories now and then every interval in the
+// background, converting added and changed files and forgetting removed
+// ones.
+func (c *Cache) Watch(dirs []string, interval time.Duration) {
+	go func() {
+		for {
+			for _, dir := range dirs {
+				if err := c.Scan(dir); err != nil {
+					log.Print(err)
+				}
+			}
+			c.prune()
+			time.Sleep(interval)
+		}
+	}()
+}
+
+// list returns the media files of dir.
+func list(dir string) ([]os.FileInfo, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	var files []os.FileInfo
+	for _, fi := range infos {
+		if fi.Mode().IsRegular() && isMedia(fi.Name()) {
+			files = append(files, fi)
+		}
+	}
+	return files, nil
+}
+
+func isMedia(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	for _, e := range Extensions {
+		if ext == e {
+			return true
+		}
+	}
+	return false
+}
+
+// Scan converts all new and changed files of dir.
+func (c *Cache) Scan(dir string) error {
+	files, err := list(dir)
+	if err != nil {
+		return err
+	}
+	fo := DirFit(dir, c.Fit)
+
+	found := map[string]bool{}
+	var pending []string
+	c.mu.Lock()
+	for _, fi := range files {
+		fname := filepath.Join(dir, fi.Name())
+		found[fname] = true
+		// changed files keep their old frames till they are converted
+		e, ok := c.entries[fname]
+		if !ok || !e.mod.Equal(fi.ModTime()) || e.size != fi.Size() || e.fo != fo {
+			pending = append(pending, fname)
+		}
+	}
+	for fname := range c.entries {
+		if filepath.Dir(fname) == filepath.Clean(dir) && !found[fname] {
+			delete(c.entries, fname)
+		}
+	}
+	c.mu.Unlock()
+
+	for _, fname := range pending {
+		if _, err := c.load(fname); err != nil {
+			log.Printf("%s: %s", fname, err)
+		}
+	}
+	return nil
+}
+
+// Clip returns the frames of fname from the cache. Files that are not
+// converted yet are converted now, or when they are converted in the
+// background, Clip waits for it.
+func (c *Cache) Clip(fname string) (*anim.Clip, error) {
+	c.mu.Lock()
+	e, ok := c.entries[fname]
+	c.mu.Unlock()
+	if ok {
+		if clip, err := c.read(e.key); err == nil {
+			return clip, nil
+		}
+	}
+	return c.load(fname)
+}
+
+func (c *Cache) path(key string) string {
+	return filepath.Join(c.Dir, key+".frames")
+}
+
+func (c *Cache) read(key string) (*anim.Clip, error) {
+	f, err := os.Open(c.path(key))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadFrames(f)
+}
+
+// load returns the frames of fname, from the cache if the content was
+// converted before. Concurrent loads of a file share one conversion.
+func (c *Cache) load(fname string) (*anim.Clip, error) {
+	c.mu.Lock()
+	if l, ok := c.loading[fname]; ok {
+		c.mu.Unlock()
+		<-l.done
+		return l.clip, l.err
+	}
+	l := &loadCall{done: make(chan struct{})}
+	c.loading[fname] = l
+	c.mu.Unlock()
+
+	l.clip, l.err = c.convert(fname)
+
+	c.mu.Lock()
+	delete(c.loading, fname)
+	c.mu.Unlock()
+	close(l.done)
+	return l.clip, l.err
+}
+
+func (c *Cache) convert(fname string) (*anim.Clip, error) {
+	fi, err := os.Stat(fname)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	fo := DirFit(filepath.Dir(fname), c.Fit)
+	sum := sha256.Sum256(data)
+	opts := sha256.Sum256([]byte(fmt.Sprintf("%dx%d %s", c.W, c.H, fo)))
+	key := hex.EncodeToString(sum[:12]) + "-" + hex.EncodeToString(opts[:4])
+
+	// record the key first, so that prune keeps the file that is written
+	c.mu.Lock()
+	c.entries[fname] = entry{mod: fi.ModTime(), size: fi.Size(), fo: fo, key: key}
+	c.mu.Unlock()
+
+	clip, err := c.read(key)
+	if err != nil {
+		if clip, err = Decode(data); err != nil {
+			return nil, err
+		}
+		clip = clip.Fit(c.W, c.H, fo)
+		if err := c.write(key, clip); err != nil {
+			log.Printf("%s: %s", fname, err)
+		}
+	}
+	return clip, nil
+}
+
+// write stores the clip atomically, so that readers never see partial
+// files.
+func (c *Cache) write(key string, clip *anim.Clip) error {
+	tmp, err := ioutil.TempFile(c.Dir, key+".tmp")
+	if err != nil {
+		return err
+	}
+	if err := WriteFrames(tmp, clip); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), c.path(key))
+}
+
+// prune removes cached frames of files that no longer exist.
+func (c *Cache) prune() {
+	used := map[string]bool{}
+	c.mu.Lock()
+	for _, e := range c.entries {
+		used[c.path(e.key)] = true
+	}
+	c.mu.Unlock()
+	cached, _ := filepath.Glob(filepath.Join(c.Dir, "*.frames"))
+	for _, fname := range cached {
+		if !used[fname] {
+			os.Remove(fname)
+		}
+	}
+}
+
+// Decode returns the frames of an image, gif or animated png in their
+// original size. Still images are a single frame.
+func Decode(data []byte) (*anim.Clip, error) {
+	_, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if format == "gif" {
+		return anim.DecodeGIF(bytes.NewReader(data))
+	}
+	if format == "png" && anim.IsAPNG(data) {
+		return anim.DecodeAPNG(bytes.NewReader(data))
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	rgba := image.NewRGBA(image.Rect(0, 0, img.Bounds().Dx(), img.Bounds().Dy()))
+	draw.Draw(rgba, rgba.Bounds(), img, img.Bounds().Min, draw.Src)
+	return &anim.Clip{Frames: []anim.Frame{{Image: rgba, Delay: anim.DefaultDelay}}}, nil
+}
@@ -0,0 +1,92 @@
+package media
+
+import (
+	"bufio"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"image"
+	"io"
+	"time"
+
+	"github.com/ktt-ol/go-insta/anim"
+)
+
+const framesMagic = "INSTAFR1"
+
+type framesHeader struct {
+	W, H   uint16
+	Loops  uint16
+	Frames uint32
+}
+
+// WriteFrames stores the opaque frames of c as gzip compressed RGB
+// values. All frames need to be of the same size.
+func WriteFrames(w io.Writer, c *anim.Clip) error {
+	zw := gzip.NewWriter(w)
+	bw := bufio.NewWriter(zw)
+	h := framesHeader{Loops: uint16(c.Loops), Frames: uint32(len(c.Frames))}
+	if len(c.Frames) > 0 {
+		b := c.Frames[0].Image.Bounds()
+		h.W, h.H = uint16(b.Dx()), uint16(b.Dy())
+	}
+	bw.WriteString(framesMagic)
+	binary.Write(bw, binary.LittleEndian, h)
+	rgb := make([]byte, int(h.W)*int(h.H)*3)
+	for _, f := range c.Frames {
+		img := f.Image
+		if b := img.Bounds(); b.Dx() != int(h.W) || b.Dy() != int(h.H) {
+			return errors.New("frames of different size")
+		}
+		for y := 0; y < int(h.H); y++ {
+			row := img.Pix[img.PixOffset(img.Rect.Min.X, img.Rect.Min.Y+y):]
+			for x := 0; x < int(h.W); x++ {
+				copy(rgb[(y*int(h.W)+x)*3:], row[x*4:x*4+3])
+			}
+		}
+		binary.Write(bw, binary.LittleEndian, uint32(f.Delay/time.Millisecond))
+		bw.Write(rgb)
+	}
+	if err := bw.Flush(); err != nil {
+		return err
+	}
+	return zw.Close()
+}
+
+// ReadFrames reads frames stored with WriteFrames.
+func ReadFrames(r io.Reader) (*anim.Clip, error) {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	br := bufio.NewReader(zr)
+	magic := make([]byte, len(framesMagic))
+	if _, err := io.ReadFull(br, magic); err != nil {
+		return nil, err
+	}
+	if string(magic) != framesMagic {
+		return nil, errors.New("not a frames file")
+	}
+	var h framesHeader
+	if err := binary.Read(br, binary.LittleEndian, &h); err != nil {
+		return nil, err
+	}
+	c := &anim.Clip{Loops: int(h.Loops)}
+	rgb := make([]byte, int(h.W)*int(h.H)*3)
+	for i := 0; i < int(h.Frames); i++ {
+		var delay uint32
+		if err := binary.Read(br, binary.LittleEndian, &delay); err != nil {
+			return nil, err
+		}
+		if _, err := io.ReadFull(br, rgb); err != nil {
+			return nil, err
+		}
+		img := image.NewRGBA(image.Rect(0, 0, int(h.W), int(h.H)))
+		for j := 0; j < len(rgb)/3; j++ {
+			copy(img.Pix[j*4:], rgb[j*3:j*3+3])
+			img.Pix[j*4+3] = 255
+		}
+		c.Frames = append(c.Frames, anim.Frame{Image: img, Delay: time.Duration(delay) * time.Millisecond})
+	}
+	return c, nil
+}
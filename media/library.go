@@ -0,0 +1,201 @@
+package media
+
+import (
+	"bufio"
+	"log"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// TagsFile is the sidecar file of a library directory. Each line is a file
+// name or glob pattern followed by tags and an optional weight:
+//
+//	# comment
+//	cat*.gif cats funny
+//	party.gif weight=3
+//	boring.png weight=0
+//
+// Files get the tags of all matching lines, the weight of the last one.
+// The default weight is 1, 0 removes the file from the rotation.
+const TagsFile = ".tags"
+
+// Item is a media file of a library.
+type Item struct {
+	Path   string
+	Weight int
+	Tags   []string
+}
+
+// HasTag returns whether the item is tagged with tag. All items have the
+// empty tag.
+func (it Item) HasTag(tag string) bool {
+	if tag == "" {
+		return true
+	}
+	for _, t := range it.Tags {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// Library plays the media files of a directory in shuffled order. Each
+// file is played as often as its weight before any file repeats, and the
+// same file is not played twice in a row, if there are others.
+type Library struct {
+	Dir string
+
+	mu      sync.Mutex
+	items   []Item
+	dirMod  time.Time
+	tagsMod time.Time
+	// bags are the remaining files per tag
+	bags map[string][]string
+	last map[string]string
+}
+
+// NewLibrary returns a library of the media files in dir.
+func NewLibrary(dir string) *Library {
+	return &Library{Dir: dir, bags: map[string][]string{}, last: map[string]string{}}
+}
+
+// Items returns the files with the tag, "" returns all.
+func (l *Library) Items(tag string) []Item {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refresh()
+	var items []Item
+	for _, it := range l.items {
+		if it.HasTag(tag) {
+			items = append(items, it)
+		}
+	}
+	return items
+}
+
+// Next returns the next file with the tag, "" selects all files. It
+// returns false if there is no file with the tag.
+func (l *Library) Next(tag string) (string, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.refresh()
+
+	bag := l.bags[tag]
+	if len(bag) == 0 {
+		bag = l.fill(tag)
+		if len(bag) == 0 {
+			return "", false
+		}
+	}
+	// avoid repeating the last file across bag boundaries and for
+	// weighted files
+	if bag[0] == l.last[tag] {
+		for i := 1; i < len(bag); i++ {
+			if bag[i] != bag[0] {
+				bag[0], bag[i] = bag[i], bag[0]
+				break
+			}
+		}
+	}
+	fname := bag[0]
+	l.bags[tag] = bag[1:]
+	l.last[tag] = fname
+	return fname, true
+}
+
+func (l *Library) fill(tag string) []string {
+	var bag []string
+	for _, it := range l.items {
+		if !it.HasTag(tag) {
+			continue
+		}
+		for i := 0; i < it.Weight; i++ {
+			bag = append(bag, it.Path)
+		}
+	}
+	rand.Shuffle(len(bag), func(i, j int) { bag[i], bag[j] = bag[j], bag[i] })
+	return bag
+}
+
+// refresh reloads the items if files were added or removed, or the tags
+// changed. All bags start over after a reload.
+func (l *Library) refresh() {
+	var dirMod, tagsMod time.Time
+	if fi, err := os.Stat(l.Dir); err == nil {
+		dirMod = fi.ModTime()
+	}
+	if fi, err := os.Stat(filepath.Join(l.Dir, TagsFile)); err == nil {
+		tagsMod = fi.ModTime()
+	}
+	if l.items != nil && dirMod.Equal(l.dirMod) && tagsMod.Equal(l.tagsMod) {
+		return
+	}
+	l.dirMod, l.tagsMod = dirMod, tagsMod
+
+	files, err := list(l.Dir)
+	if err != nil {
+		log.Print(err)
+	}
+	l.items = make([]Item, 0, len(files))
+	for _, fi := range files {
+		l.items = append(l.items, Item{Path: filepath.Join(l.Dir, fi.Name()), Weight: 1})
+	}
+	l.readTags()
+	l.bags = map[string][]string{}
+}
+
+func (l *Library) readTags() {
+	fname := filepath.Join(l.Dir, TagsFile)
+	f, err := os.Open(fname)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	for line := 1; s.Scan(); line++ {
+		fields := strings.Fields(s.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		pattern := fields[0]
+		if _, err := filepath.Match(pattern, ""); err != nil {
+			log.Printf("%s:%d: %s", fname, line, err)
+			continue
+		}
+		weight := -1
+		var tags []string
+		for _, f := range fields[1:] {
+			if v := strings.TrimPrefix(f, "weight="); v != f {
+				w, err := strconv.Atoi(v)
+				if err != nil || w < 0 {
+					log.Printf("%s:%d: invalid weight %q", fname, line, v)
+					continue
+				}
+				weight = w
+				continue
+			}
+			tags = append(tags, f)
+		}
+		matched := false
+		for i := range l.items {
+			it := &l.items[i]
+			if ok, _ := filepath.Match(pattern, filepath.Base(it.Path)); !ok {
+				continue
+			}
+			matched = true
+			it.Tags = append(it.Tags, tags...)
+			if weight >= 0 {
+				it.Weight = weight
+			}
+		}
+		if !matched {
+			log.Printf("%s:%d: no file matches %q", fname, line, pattern)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package insta
+
+import (
+	"image/draw"
+	"time"
+
+	"github.com/ktt-ol/go-insta/timing"
+)
+
+// Mode draws the frames of an animation. Draw is called with the time since
+// the start of the mode and draws into dst, which is either the whole
+// Screen or a View with its own size. The bounds of dst start at 0/0.
+type Mode interface {
+	Draw(dst draw.Image, el time.Duration)
+}
+
+// ModeFunc is a function that implements Mode.
+type ModeFunc func(dst draw.Image, el time.Duration)
+
+func (f ModeFunc) Draw(dst draw.Image, el time.Duration) {
+	f(dst, el)
+}
+
+// RunMode sends the frames of m to c for the duration d.
+func RunMode(c Client, m Mode, d time.Duration) {
+	start := timing.Now()
+	for el := time.Duration(0); el < d; el = timing.Since(start) {
+		scr := NewScreen()
+		m.Draw(scr, el)
+		c.SetScreen(scr)
+	}
+}
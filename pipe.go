@@ -0,0 +1,244 @@
+package insta
+
+import (
+	"bufio"
+	"fmt"
+	"image/color"
+	"io"
+	"log"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ParseHexColor parses colors like "ff8000" or "#ff8000".
+func ParseHexColor(s string) (color.RGBA, error) {
+	s = strings.TrimPrefix(s, "#")
+	if len(s) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected RRGGBB", s)
+	}
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid color %q, expected RRGGBB", s)
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, nil
+}
+
+// ColorRule colors all lines that match Re.
+type ColorRule struct {
+	Re    *regexp.Regexp
+	Color color.RGBA
+}
+
+// ColorRules is a flag.Value for rules like "ERROR=ff0000". The flag can be
+// repeated, the first matching rule wins.
+type ColorRules []ColorRule
+
+func (r *ColorRules) String() string {
+	var rules []string
+	for _, rule := range *r {
+		rules = append(rules, fmt.Sprintf("%s=%02x%02x%02x", rule.Re, rule.Color.R, rule.Color.G, rule.Color.B))
+	}
+	return strings.Join(rules, " ")
+}
+
+func (r *ColorRules) Set(s string) error {
+	i := strings.LastIndex(s, "=")
+	if i < 0 {
+		return fmt.Errorf("invalid color rule %q, expected REGEXP=RRGGBB", s)
+	}
+	re, err := regexp.Compile(s[:i])
+	if err != nil {
+		return err
+	}
+	c, err := ParseHexColor(s[i+1:])
+	if err != nil {
+		return err
+	}
+	*r = append(*r, ColorRule{Re: re, Color: c})
+	return nil
+}
+
+// Color returns the color of the first matching rule or def.
+func (r ColorRules) Color(line string, def color.RGBA) color.RGBA {
+	for _, rule := range r {
+		if rule.Re.MatchString(line) {
+			return rule.Color
+		}
+	}
+	return def
+}
+
+// Pipe scrolls lines read from an input (e.g. `tail -f`) across the screen.
+// Lines are queued and the scrolling speeds up and long lines are shortened
+// when the queue grows.
+type Pipe struct {
+	Rules ColorRules
+	// ScrollSpeed in pixels per second without backlog.
+	ScrollSpeed float64
+	// MaxSpeed limits the scroll speed with backlog. 0 is six times the
+	// ScrollSpeed, lines never scroll slower than ScrollSpeed.
+	MaxSpeed float64
+	// MaxQueue is the number of queued lines, older lines are dropped.
+	MaxQueue int
+	// ShortenAt is the backlog size from which lines are shortened to
+	// MaxLength characters, 0 disables shortening.
+	ShortenAt int
+	MaxLength int
+
+	mu      sync.Mutex
+	queue   []string
+	dropped int
+	ready   chan struct{}
+	done    bool
+}
+
+func NewPipe() *Pipe {
+	return &Pipe{
+		ScrollSpeed: 25,
+		MaxQueue:    500,
+		ShortenAt:   10,
+		MaxLength:   60,
+		ready:       make(chan struct{}, 1),
+	}
+}
+
+// maxLineSize is the number of bytes kept of a line, the rest is skipped.
+const maxLineSize = 4096
+
+// Read queues all lines of r till EOF.
+func (p *Pipe) Read(r io.Reader) error {
+	br := bufio.NewReader(r)
+	for {
+		text, err := readLine(br, maxLineSize)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			p.finish()
+			return err
+		}
+		line := strings.TrimSpace(strings.Replace(text, "\t", " ", -1))
+		if line == "" {
+			continue
+		}
+		p.mu.Lock()
+		if len(p.queue) >= p.MaxQueue {
+			p.queue = p.queue[1:]
+			p.dropped++
+		}
+		p.queue = append(p.queue, line)
+		p.mu.Unlock()
+		p.notify()
+	}
+	p.finish()
+	return nil
+}
+
+// readLine returns the next line of r, without the rest of lines longer
+// than max bytes.
+func readLine(r *bufio.Reader, max int) (string, error) {
+	var line []byte
+	for {
+		part, more, err := r.ReadLine()
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				return string(line), nil
+			}
+			return "", err
+		}
+		if n := max - len(line); n > 0 {
+			if len(part) > n {
+				part = part[:n]
+			}
+			line = append(line, part...)
+		}
+		if !more {
+			return string(line), nil
+		}
+	}
+}
+
+func (p *Pipe) finish() {
+	p.mu.Lock()
+	p.done = true
+	p.mu.Unlock()
+	p.notify()
+}
+
+func (p *Pipe) notify() {
+	select {
+	case p.ready <- struct{}{}:
+	default:
+	}
+}
+
+// next returns the next line and the number of lines that are still queued.
+// It blocks till a line is available and returns false after the input
+// ended and all lines were shown.
+func (p *Pipe) next() (string, int, bool) {
+	for {
+		p.mu.Lock()
+		if p.dropped > 0 {
+			log.Printf("pipe: dropped %d lines", p.dropped)
+			p.dropped = 0
+		}
+		if len(p.queue) > 0 {
+			line := p.queue[0]
+			p.queue = p.queue[1:]
+			backlog := len(p.queue)
+			p.mu.Unlock()
+			return line, backlog, true
+		}
+		done := p.done
+		p.mu.Unlock()
+		if done {
+			return "", 0, false
+		}
+		<-p.ready
+	}
+}
+
+// shorten cuts line to n characters, ending with "..." if there is room.
+func shorten(line string, n int) string {
+	r := []rune(line)
+	if n <= 0 || len(r) <= n {
+		return line
+	}
+	if n <= 3 {
+		return string(r[:n])
+	}
+	return string(r[:n-3]) + "..."
+}
+
+// Run reads lines from r and shows them till r ends.
+func (p *Pipe) Run(c Client, r io.Reader) error {
+	errc := make(chan error, 1)
+	go func() { errc <- p.Read(r) }()
+
+	for {
+		line, backlog, ok := p.next()
+		if !ok {
+			break
+		}
+		if p.ShortenAt > 0 && backlog >= p.ShortenAt {
+			line = shorten(line, p.MaxLength)
+		}
+		t := NewText("", TextScroll)
+		// show the line verbatim, without highlight markup
+		t.Lines = []string{line}
+		t.Color = p.Rules.Color(line, t.Color)
+		max := p.MaxSpeed
+		if max <= 0 {
+			max = 6 * p.ScrollSpeed
+		}
+		t.ScrollSpeed = p.ScrollSpeed * (1 + float64(backlog)/4)
+		if t.ScrollSpeed > max {
+			t.ScrollSpeed = math.Max(max, p.ScrollSpeed)
+		}
+		PlayText(c, t)
+	}
+	return <-errc
+}
@@ -2,31 +2,56 @@ package insta
 
 import (
 	"image/color"
+	"image/draw"
 	"math"
 	"math/rand"
 	"time"
+
+	"github.com/ktt-ol/go-insta/theme"
 )
 
-func Rainbow(c Client, d time.Duration) {
-	start := time.Now()
-	ix := rand.Float64() * 100
-	iy := rand.Float64() * 50
-	ih := rand.Float64() * 360
-	for time.Since(start) < d {
-		s := NewScreen()
-		for x := 0; x < ScreenWidth; x++ {
-			for y := 0; y < ScreenHeight; y++ {
-				r, g, b := HsvToRgb(
-					float64(y)*1.5+ih,
-					0.2+0.8*(math.Sin((ix+float64(x))/ScreenWidth*3)/2+0.5),
-					0.1+0.9+(math.Cos((iy+float64(y))/ScreenHeight*3)/2+0.5),
-				)
-				s.Set(x, y, color.RGBA{uint8(r * 255), uint8(g * 255), uint8(b * 255), 128})
-			}
+// shade applies the saturation s and value v (0-1) of HSV colors to c in
+// sRGB, to use theme colors where modes used HSV before.
+func shade(c color.RGBA, s, v float64) color.RGBA {
+	mix := func(b uint8) uint8 { return uint8((255-(255-float64(b))*s)*v + 0.5) }
+	return color.RGBA{mix(c.R), mix(c.G), mix(c.B), c.A}
+}
+
+// RainbowMode is a slowly moving rainbow in the colors of the theme.
+type RainbowMode struct {
+	ix, iy, ih float64
+}
+
+// NewRainbow returns a rainbow with a random start.
+func NewRainbow() *RainbowMode {
+	return &RainbowMode{
+		ix: rand.Float64() * 100,
+		iy: rand.Float64() * 50,
+		ih: rand.Float64() * 360,
+	}
+}
+
+func (m *RainbowMode) Draw(dst draw.Image, el time.Duration) {
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	sec := el.Seconds()
+	ix := m.ix + 20*sec
+	iy := m.iy + 12.5*sec
+	ih := m.ih + 50*sec
+	pal := theme.Current().Cycled()
+	for x := 0; x < w; x++ {
+		for y := 0; y < h; y++ {
+			// the hue comes from the theme, saturation and value wave
+			// across
+			c := shade(pal.Hue(math.Mod(float64(y)*1.5+ih, 360)),
+				0.2+0.8*(math.Sin((ix+float64(x))/float64(w)*3)/2+0.5),
+				0.1+0.9*(math.Cos((iy+float64(y))/float64(h)*3)/2+0.5),
+			)
+			c.A = 128
+			dst.Set(x, y, c)
 		}
-		ix += 0.8
-		iy += 0.5
-		ih += 2.0
-		c.SetScreen(s)
 	}
 }
+
+func Rainbow(c Client, d time.Duration) {
+	RunMode(c, NewRainbow(), d)
+}
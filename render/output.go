@@ -0,0 +1,147 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"image/gif"
+	"image/png"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ktt-ol/go-insta"
+)
+
+// Style is the look of the output. Scale enlarges each LED to Scale x Scale
+// pixels, Dots draws round LEDs with dark gaps instead of squares.
+type Style struct {
+	Scale int
+	Dots  bool
+}
+
+// dot returns whether the pixel x/y inside of an LED is lit.
+func (st Style) dot(x, y int) bool {
+	if !st.Dots || st.Scale < 3 {
+		return true
+	}
+	c := float64(st.Scale) / 2
+	dx, dy := float64(x)+0.5-c, float64(y)+0.5-c
+	return math.Hypot(dx, dy) <= c*0.8
+}
+
+func (st Style) scale() int {
+	if st.Scale < 1 {
+		return 1
+	}
+	return st.Scale
+}
+
+// upscale returns the screen with the style as RGBA image.
+func (st Style) upscale(s *insta.Screen) *image.RGBA {
+	k := st.scale()
+	dst := image.NewRGBA(image.Rect(0, 0, insta.ScreenWidth*k, insta.ScreenHeight*k))
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			o := dst.PixOffset(x, y)
+			dst.Pix[o+3] = 255
+			if !st.dot(x%k, y%k) {
+				continue
+			}
+			so := (y/k*insta.ScreenWidth + x/k) * 3
+			copy(dst.Pix[o:o+3], s.Pix[so:so+3])
+		}
+	}
+	return dst
+}
+
+// upscalePaletted returns the paletted screen p with the style. gap is the
+// palette index of black.
+func (st Style) upscalePaletted(p *image.Paletted, gap uint8) *image.Paletted {
+	k := st.scale()
+	dst := image.NewPaletted(image.Rect(0, 0, p.Rect.Dx()*k, p.Rect.Dy()*k), p.Palette)
+	for y := 0; y < dst.Rect.Dy(); y++ {
+		for x := 0; x < dst.Rect.Dx(); x++ {
+			i := gap
+			if st.dot(x%k, y%k) {
+				i = p.ColorIndexAt(x/k, y/k)
+			}
+			dst.SetColorIndex(x, y, i)
+		}
+	}
+	return dst
+}
+
+// WriteGIF writes the frames till d as animated gif. Each frame has its
+// own palette, see Quantize.
+func WriteGIF(fname string, frames []Frame, d time.Duration, st Style) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to write")
+	}
+	g := &gif.GIF{}
+	// delays in 1/100 s, rounded from the start to avoid drift
+	cs := func(t time.Duration) int { return int(t.Round(10*time.Millisecond) / (10 * time.Millisecond)) }
+	for i, f := range frames {
+		end := d
+		if i+1 < len(frames) {
+			end = frames[i+1].At
+		}
+		delay := cs(end) - cs(f.At)
+		if delay <= 0 {
+			continue
+		}
+		p, gap := Quantize(f.Screen, 256)
+		g.Image = append(g.Image, st.upscalePaletted(p, gap))
+		g.Delay = append(g.Delay, delay)
+	}
+	w, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	if err := gif.EncodeAll(w, g); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// WritePNGs writes the frames till d as png sequence with fps frames per
+// second. pattern is the file name with a verb for the frame number, like
+// "frames/%04d.png", or the name to which the frame number is appended.
+func WritePNGs(pattern string, frames []Frame, d time.Duration, fps int, st Style) error {
+	if len(frames) == 0 {
+		return fmt.Errorf("no frames to write")
+	}
+	if !strings.Contains(pattern, "%") {
+		ext := filepath.Ext(pattern)
+		pattern = strings.TrimSuffix(pattern, ext) + "-%04d" + ext
+	}
+	n := int(d.Seconds() * float64(fps))
+	if n < 1 {
+		n = 1
+	}
+	j := 0
+	for i := 0; i < n; i++ {
+		t := time.Duration(i) * time.Second / time.Duration(fps)
+		for j+1 < len(frames) && frames[j+1].At <= t {
+			j++
+		}
+		if err := writePNG(fmt.Sprintf(pattern, i+1), st.upscale(frames[j].Screen)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writePNG(fname string, img image.Image) error {
+	w, err := os.Create(fname)
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(w, img); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
@@ -0,0 +1,143 @@
+package render
+
+import (
+	"image"
+	"image/color"
+	"sort"
+
+	"github.com/ktt-ol/go-insta"
+)
+
+var black = color.RGBA{0, 0, 0, 255}
+
+// Quantize returns the screen with a palette of at most n colors, which
+// always includes black. Screens with few colors are exact, others use a
+// median cut palette. It returns the palette index of black.
+func Quantize(s *insta.Screen, n int) (*image.Paletted, uint8) {
+	counts := map[color.RGBA]int{black: 0}
+	for i := 0; i < len(s.Pix); i += 3 {
+		counts[color.RGBA{s.Pix[i], s.Pix[i+1], s.Pix[i+2], 255}]++
+	}
+
+	var pal color.Palette
+	if len(counts) <= n {
+		for c := range counts {
+			pal = append(pal, c)
+		}
+		// stable order for reproducible files
+		sort.Slice(pal, func(i, j int) bool { return less(pal[i].(color.RGBA), pal[j].(color.RGBA)) })
+	} else {
+		delete(counts, black)
+		pal = append(medianCut(counts, n-1), black)
+	}
+
+	p := image.NewPaletted(image.Rect(0, 0, insta.ScreenWidth, insta.ScreenHeight), pal)
+	index := map[color.RGBA]uint8{}
+	for i := 0; i < len(s.Pix); i += 3 {
+		c := color.RGBA{s.Pix[i], s.Pix[i+1], s.Pix[i+2], 255}
+		idx, ok := index[c]
+		if !ok {
+			idx = uint8(pal.Index(c))
+			index[c] = idx
+		}
+		p.Pix[i/3] = idx
+	}
+	return p, uint8(pal.Index(black))
+}
+
+func less(a, b color.RGBA) bool {
+	if a.R != b.R {
+		return a.R < b.R
+	}
+	if a.G != b.G {
+		return a.G < b.G
+	}
+	return a.B < b.B
+}
+
+type weighted struct {
+	c color.RGBA
+	n int
+}
+
+// medianCut splits the colors into n boxes, each time halving the box with
+// the widest channel range at the pixel count median, and returns the
+// weighted mean color of each box.
+func medianCut(counts map[color.RGBA]int, n int) color.Palette {
+	all := make([]weighted, 0, len(counts))
+	for c, k := range counts {
+		all = append(all, weighted{c, k})
+	}
+	sort.Slice(all, func(i, j int) bool { return less(all[i].c, all[j].c) })
+	boxes := [][]weighted{all}
+	for len(boxes) < n {
+		best, bestRange, bestCh := -1, 0, 0
+		for i, b := range boxes {
+			if len(b) < 2 {
+				continue
+			}
+			for ch := 0; ch < 3; ch++ {
+				lo, hi := channelRange(b, ch)
+				if hi-lo > bestRange {
+					best, bestRange, bestCh = i, hi-lo, ch
+				}
+			}
+		}
+		if best < 0 {
+			break
+		}
+		b := boxes[best]
+		sort.SliceStable(b, func(i, j int) bool { return channel(b[i].c, bestCh) < channel(b[j].c, bestCh) })
+		total := 0
+		for _, w := range b {
+			total += w.n
+		}
+		split, acc := 1, 0
+		for i, w := range b[:len(b)-1] {
+			acc += w.n
+			split = i + 1
+			if acc*2 >= total {
+				break
+			}
+		}
+		boxes[best] = b[:split]
+		boxes = append(boxes, b[split:])
+	}
+
+	pal := make(color.Palette, len(boxes))
+	for i, b := range boxes {
+		var r, g, bl, total int
+		for _, w := range b {
+			r += int(w.c.R) * w.n
+			g += int(w.c.G) * w.n
+			bl += int(w.c.B) * w.n
+			total += w.n
+		}
+		pal[i] = color.RGBA{uint8(r / total), uint8(g / total), uint8(bl / total), 255}
+	}
+	return pal
+}
+
+func channel(c color.RGBA, ch int) int {
+	switch ch {
+	case 0:
+		return int(c.R)
+	case 1:
+		return int(c.G)
+	}
+	return int(c.B)
+}
+
+func channelRange(b []weighted, ch int) (lo, hi int) {
+	lo, hi = 255, 0
+	for _, w := range b {
+		v := channel(w.c, ch)
+		if v < lo {
+			lo = v
+		}
+		if v > hi {
+			hi = v
+		}
+	}
+	return lo, hi
+}
@@ -0,0 +1,75 @@
+// Package render records modes offline against a virtual clock and writes
+// them as animated gif or png sequence, e.g. for previews without the
+// wall.
+package render
+
+import (
+	"bytes"
+	"time"
+
+	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/timing"
+)
+
+// Frame is a screen and the time it was sent since the start of the
+// recording.
+type Frame struct {
+	Screen *insta.Screen
+	At     time.Duration
+}
+
+// Recorder is a Client that records all screens. SetScreen advances the
+// virtual clock by one frame, like the real client waits for the next
+// frame.
+type Recorder struct {
+	Clock  *timing.Virtual
+	Frames []Frame
+
+	fps   int
+	start time.Time
+}
+
+// NewRecorder returns a recorder with the frame rate fps, that starts at
+// the current time of clock.
+func NewRecorder(clock *timing.Virtual, fps int) *Recorder {
+	return &Recorder{Clock: clock, fps: fps, start: clock.Now()}
+}
+
+func (r *Recorder) record(s *insta.Screen) {
+	f := Frame{Screen: s.Copy(), At: r.Clock.Now().Sub(r.start)}
+	if n := len(r.Frames); n > 0 && r.Frames[n-1].At == f.At {
+		// only the last screen of a point in time is visible
+		r.Frames[n-1] = f
+		return
+	}
+	if n := len(r.Frames); n > 0 && bytes.Equal(r.Frames[n-1].Screen.Pix, f.Screen.Pix) {
+		return
+	}
+	r.Frames = append(r.Frames, f)
+}
+
+func (r *Recorder) SetScreen(s *insta.Screen) {
+	r.record(s)
+	r.Clock.Advance(time.Second / time.Duration(r.fps))
+}
+
+func (r *Recorder) SetScreenImmediate(s *insta.Screen) {
+	r.record(s)
+}
+
+// SetFPS is ignored, the frame rate of the recording is fixed.
+func (r *Recorder) SetFPS(int) {}
+
+func (r *Recorder) Run() {}
+
+func (r *Recorder) SetAfterglow(float64) {}
+
+// Until returns the frames before d.
+func (r *Recorder) Until(d time.Duration) []Frame {
+	for i, f := range r.Frames {
+		if f.At >= d {
+			return r.Frames[:i]
+		}
+	}
+	return r.Frames
+}
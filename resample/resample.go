@@ -0,0 +1,433 @@
+// Package resample scales images with filters that work well for the tiny
+// LED wall: area averaging for photos, Lanczos with sharpening for small
+// scale factors and nearest neighbour for pixel art. Filtering is done in
+// linear light.
+package resample
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"math"
+	"strings"
+
+	"github.com/ktt-ol/go-insta/colors"
+)
+
+type Filter int
+
+const (
+	// Auto picks one of the other filters with Choose.
+	Auto Filter = iota
+	// Box averages all source pixels covered by a target pixel.
+	Box
+	// Lanczos uses a Lanczos3 kernel followed by an unsharp mask.
+	Lanczos
+	// Nearest picks the source pixel at the center of the target pixel.
+	Nearest
+)
+
+var filterNames = []string{"auto", "box", "lanczos", "nearest"}
+
+func (f Filter) String() string {
+	if int(f) < len(filterNames) {
+		return filterNames[f]
+	}
+	return fmt.Sprintf("Filter(%d)", int(f))
+}
+
+// ParseFilter returns the filter for the name as returned by
+// Filter.String.
+func ParseFilter(s string) (Filter, error) {
+	for i, n := range filterNames {
+		if n == s {
+			return Filter(i), nil
+		}
+	}
+	return 0, fmt.Errorf("unknown filter %q, expected one of %s", s, strings.Join(filterNames, ", "))
+}
+
+// Choose returns the filter for scaling img to w x h. Pixel art that is
+// scaled by an integer factor uses Nearest, as well as integer upscaling.
+// Large reductions use Box, everything else Lanczos.
+func Choose(img image.Image, w, h int) Filter {
+	b := img.Bounds()
+	if b.Dx() == 0 || b.Dy() == 0 || w == 0 || h == 0 {
+		return Nearest
+	}
+	if w%b.Dx() == 0 && h%b.Dy() == 0 {
+		return Nearest
+	}
+	if PixelScale(img) > 1 {
+		return Nearest
+	}
+	if b.Dx() >= 3*w && b.Dy() >= 3*h {
+		return Box
+	}
+	return Lanczos
+}
+
+// PixelScale returns the largest factor k, so that img consists of k x k
+// blocks of identical pixels. Upscaled pixel art returns its scale factor,
+// other images return 1.
+func PixelScale(img image.Image) int {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	for k := 16; k > 1; k-- {
+		if w%k != 0 || h%k != 0 || w/k < 2 || h/k < 2 {
+			continue
+		}
+		if isScaled(img, k) {
+			return k
+		}
+	}
+	return 1
+}
+
+func isScaled(img image.Image, k int) bool {
+	b := img.Bounds()
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		by := b.Min.Y + (y-b.Min.Y)/k*k
+		for x := b.Min.X; x < b.Max.X; x++ {
+			bx := b.Min.X + (x-b.Min.X)/k*k
+			if x == bx && y == by {
+				continue
+			}
+			r0, g0, b0, a0 := img.At(bx, by).RGBA()
+			r, g, bl, a := img.At(x, y).RGBA()
+			if r != r0 || g != g0 || bl != b0 || a != a0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Resize returns img scaled to w x h with the filter f. If w or h is 0, it
+// is computed from the aspect ratio of img.
+func Resize(img image.Image, w, h int, f Filter) *image.RGBA {
+	b := img.Bounds()
+	if w == 0 && b.Dy() > 0 {
+		w = b.Dx() * h / b.Dy()
+	} else if h == 0 && b.Dx() > 0 {
+		h = b.Dy() * w / b.Dx()
+	}
+	if f == Auto {
+		f = Choose(img, w, h)
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	if f == Nearest {
+		return nearest(img, w, h)
+	}
+	src := load(reduce(img, w, h))
+	var p *plane
+	switch f {
+	case Box:
+		p = src.resample(w, h, boxWeights)
+	default:
+		p = src.resample(w, h, lanczosWeights)
+		p = p.sharpen(0.5)
+	}
+	return p.rgba()
+}
+
+// reduceMargin is how much larger than the target images stay after reduce.
+const reduceMargin = 4
+
+// reduce box-reduces large images by integer factors to at least
+// reduceMargin times the target size, before they are converted to float
+// planes. The image is read in strips, so that large photos don't need a
+// full size copy. The remainder of less than one box is cut off.
+func reduce(img image.Image, w, h int) image.Image {
+	b := img.Bounds()
+	kx, ky := b.Dx()/(reduceMargin*w), b.Dy()/(reduceMargin*h)
+	if kx < 1 {
+		kx = 1
+	}
+	if ky < 1 {
+		ky = 1
+	}
+	if kx == 1 && ky == 1 {
+		return img
+	}
+	dw, dh := b.Dx()/kx, b.Dy()/ky
+	dst := image.NewRGBA(image.Rect(0, 0, dw, dh))
+	strip := image.NewRGBA(image.Rect(0, 0, dw*kx, ky))
+	sum := make([]float32, dw*4)
+	n := float32(kx * ky)
+	for dy := 0; dy < dh; dy++ {
+		draw.Draw(strip, strip.Bounds(), img, image.Pt(b.Min.X, b.Min.Y+dy*ky), draw.Src)
+		for i := range sum {
+			sum[i] = 0
+		}
+		for y := 0; y < ky; y++ {
+			row := strip.Pix[y*strip.Stride:]
+			for x := 0; x < dw*kx; x++ {
+				px := row[x*4 : x*4+4]
+				if px[3] == 0 {
+					continue
+				}
+				// average premultiplied linear light, like load
+				fa := float32(px[3]) / 255
+				o := x / kx * 4
+				for c := 0; c < 3; c++ {
+					v := uint8(math.Min(255, float64(px[c])*255/float64(px[3])+0.5))
+					sum[o+c] += colors.ToLinear(v) * fa
+				}
+				sum[o+3] += fa
+			}
+		}
+		out := dst.Pix[dy*dst.Stride:]
+		for i := 0; i < len(sum); i += 4 {
+			a := sum[i+3] / n
+			if a <= 0 {
+				continue
+			}
+			for c := 0; c < 3; c++ {
+				v := colors.FromLinear(sum[i+c] / n / a)
+				out[i+c] = uint8(float32(v)*a + 0.5)
+			}
+			out[i+3] = uint8(a*255 + 0.5)
+		}
+	}
+	return dst
+}
+
+func nearest(img image.Image, w, h int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	b := img.Bounds()
+	for y := 0; y < h; y++ {
+		sy := b.Min.Y + (2*y+1)*b.Dy()/(2*h)
+		for x := 0; x < w; x++ {
+			sx := b.Min.X + (2*x+1)*b.Dx()/(2*w)
+			dst.Set(x, y, img.At(sx, sy))
+		}
+	}
+	return dst
+}
+
+// plane is an image with premultiplied linear light RGBA float values.
+type plane struct {
+	w, h int
+	pix  []float32
+}
+
+func load(img image.Image) *plane {
+	b := img.Bounds()
+	rgba, ok := img.(*image.RGBA)
+	if !ok {
+		rgba = image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+		draw.Draw(rgba, rgba.Bounds(), img, b.Min, draw.Src)
+		b = rgba.Bounds()
+	}
+	p := &plane{w: b.Dx(), h: b.Dy(), pix: make([]float32, b.Dx()*b.Dy()*4)}
+	i := 0
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		row := rgba.Pix[rgba.PixOffset(b.Min.X, y):]
+		for x := 0; x < p.w; x++ {
+			a := row[x*4+3]
+			if a == 0 {
+				i += 4
+				continue
+			}
+			fa := float32(a) / 255
+			for c := 0; c < 3; c++ {
+				// un-premultiply before linearizing
+				v := uint8(math.Min(255, float64(row[x*4+c])*255/float64(a)+0.5))
+				p.pix[i+c] = colors.ToLinear(v) * fa
+			}
+			p.pix[i+3] = fa
+			i += 4
+		}
+	}
+	return p
+}
+
+func (p *plane) rgba() *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, p.w, p.h))
+	for i := 0; i < len(p.pix); i += 4 {
+		a := p.pix[i+3]
+		if a <= 0 {
+			continue
+		}
+		if a > 1 {
+			a = 1
+		}
+		for c := 0; c < 3; c++ {
+			v := colors.FromLinear(p.pix[i+c] / a)
+			dst.Pix[i+c] = uint8(float32(v)*a + 0.5)
+		}
+		dst.Pix[i+3] = uint8(a*255 + 0.5)
+	}
+	return dst
+}
+
+type weight struct {
+	i int
+	w float32
+}
+
+// weightFunc returns for each of the dst target positions the source
+// positions and their normalized weights.
+type weightFunc func(src, dst int) [][]weight
+
+func (p *plane) resample(w, h int, wf weightFunc) *plane {
+	wx, wy := wf(p.w, w), wf(p.h, h)
+
+	tmp := &plane{w: w, h: p.h, pix: make([]float32, w*p.h*4)}
+	for y := 0; y < p.h; y++ {
+		for x, ws := range wx {
+			o := (y*w + x) * 4
+			for _, wt := range ws {
+				s := (y*p.w + wt.i) * 4
+				for c := 0; c < 4; c++ {
+					tmp.pix[o+c] += p.pix[s+c] * wt.w
+				}
+			}
+		}
+	}
+
+	out := &plane{w: w, h: h, pix: make([]float32, w*h*4)}
+	for y, ws := range wy {
+		for x := 0; x < w; x++ {
+			o := (y*w + x) * 4
+			for _, wt := range ws {
+				s := (wt.i*w + x) * 4
+				for c := 0; c < 4; c++ {
+					out.pix[o+c] += tmp.pix[s+c] * wt.w
+				}
+			}
+		}
+	}
+	for i, v := range out.pix {
+		if v < 0 {
+			out.pix[i] = 0
+		}
+	}
+	return out
+}
+
+// boxWeights weights the source pixels by their overlap with the target
+// pixel. Upscaling repeats pixels.
+func boxWeights(src, dst int) [][]weight {
+	ws := make([][]weight, dst)
+	s := float64(src) / float64(dst)
+	for i := range ws {
+		lo, hi := float64(i)*s, float64(i+1)*s
+		for j := int(lo); j < src && float64(j) < hi; j++ {
+			o := math.Min(hi, float64(j+1)) - math.Max(lo, float64(j))
+			if o > 0 {
+				ws[i] = append(ws[i], weight{j, float32(o)})
+			}
+		}
+		normalize(ws[i])
+	}
+	return ws
+}
+
+func lanczos(x float64) float64 {
+	const a = 3
+	if x == 0 {
+		return 1
+	}
+	if x <= -a || x >= a {
+		return 0
+	}
+	px := math.Pi * x
+	return a * math.Sin(px) * math.Sin(px/a) / (px * px)
+}
+
+// lanczosWeights uses a Lanczos3 kernel, widened by the scale factor when
+// downscaling. Positions outside of the image are clamped to the edge.
+func lanczosWeights(src, dst int) [][]weight {
+	ws := make([][]weight, dst)
+	s := float64(src) / float64(dst)
+	fs := math.Max(s, 1)
+	support := 3 * fs
+	for i := range ws {
+		c := (float64(i)+0.5)*s - 0.5
+		for j := int(math.Floor(c - support)); j <= int(math.Ceil(c+support)); j++ {
+			w := lanczos((float64(j) - c) / fs)
+			if w == 0 {
+				continue
+			}
+			k := j
+			if k < 0 {
+				k = 0
+			} else if k >= src {
+				k = src - 1
+			}
+			ws[i] = append(ws[i], weight{k, float32(w)})
+		}
+		normalize(ws[i])
+	}
+	return ws
+}
+
+func normalize(ws []weight) {
+	var sum float32
+	for _, w := range ws {
+		sum += w.w
+	}
+	if sum == 0 {
+		return
+	}
+	for i := range ws {
+		ws[i].w /= sum
+	}
+}
+
+// sharpen applies an unsharp mask with a 3x3 blur, to bring back the
+// contrast of small details that is lost when scaling down.
+func (p *plane) sharpen(amount float32) *plane {
+	out := &plane{w: p.w, h: p.h, pix: make([]float32, len(p.pix))}
+	at := func(x, y, c int) float32 {
+		if x < 0 {
+			x = 0
+		} else if x >= p.w {
+			x = p.w - 1
+		}
+		if y < 0 {
+			y = 0
+		} else if y >= p.h {
+			y = p.h - 1
+		}
+		return p.pix[(y*p.w+x)*4+c]
+	}
+	for y := 0; y < p.h; y++ {
+		for x := 0; x < p.w; x++ {
+			o := (y*p.w + x) * 4
+			for c := 0; c < 4; c++ {
+				var blur float32
+				for dy := -1; dy <= 1; dy++ {
+					for dx := -1; dx <= 1; dx++ {
+						blur += at(x+dx, y+dy, c)
+					}
+				}
+				blur /= 9
+				v := p.pix[o+c] + amount*(p.pix[o+c]-blur)
+				if v < 0 {
+					v = 0
+				}
+				out.pix[o+c] = v
+			}
+			// color must not exceed alpha in premultiplied values
+			a := out.pix[o+3]
+			if a > 1 {
+				a = 1
+				out.pix[o+3] = 1
+			}
+			for c := 0; c < 3; c++ {
+				if out.pix[o+c] > a {
+					out.pix[o+c] = a
+				}
+			}
+		}
+	}
+	return out
+}
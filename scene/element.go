@@ -0,0 +1,324 @@
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/anim"
+	"github.com/ktt-ol/go-insta/clock"
+	"github.com/ktt-ol/go-insta/gfx"
+	"github.com/ktt-ol/go-insta/layer"
+	"github.com/ktt-ol/go-insta/life"
+	"github.com/ktt-ol/go-insta/resample"
+)
+
+// element draws into a transparent image. el is the time since the start
+// of the element.
+type element interface {
+	draw(dst *image.RGBA, el time.Duration)
+}
+
+// placed is an element with its visibility in the scene.
+type placed struct {
+	start, end time.Duration
+	opacity    *Value
+	e          element
+}
+
+// box is the region of an element.
+type box struct {
+	x, y, w, h *Value
+}
+
+func (b box) rect(el time.Duration) image.Rectangle {
+	x, y := b.x.Int(el), b.y.Int(el)
+	return image.Rect(x, y, x+b.w.Int(el), y+b.h.Int(el))
+}
+
+func (o *obj) box() box {
+	return box{
+		x: o.value("x", 0),
+		y: o.value("y", 0),
+		w: o.value("width", insta.ScreenWidth),
+		h: o.value("height", insta.ScreenHeight),
+	}
+}
+
+// opaque ignores the alpha of all colors. Modes like the rainbow use the
+// alpha channel for other purposes.
+type opaque struct {
+	draw.Image
+}
+
+func (o opaque) Set(x, y int, c color.Color) {
+	r, g, b, _ := c.RGBA()
+	o.Image.Set(x, y, color.RGBA{uint8(r >> 8), uint8(g >> 8), uint8(b >> 8), 255})
+}
+
+// modeElement draws a built-in mode like the rainbow into its box.
+// Modes like the stars and life keep state and expect el to grow, so the
+// mode is made new when the time jumps back, e.g. when the scene loops.
+type modeElement struct {
+	box
+	newMode func() insta.Mode
+	m       insta.Mode
+	last    time.Duration
+}
+
+func (e *modeElement) draw(dst *image.RGBA, el time.Duration) {
+	if e.m == nil || el < e.last {
+		e.m = e.newMode()
+	}
+	e.last = el
+	e.m.Draw(opaque{insta.NewView(dst, e.rect(el))}, el)
+}
+
+type textElement struct {
+	box
+	t                *insta.Text
+	color, highlight *ColorValue
+}
+
+func (e *textElement) draw(dst *image.RGBA, el time.Duration) {
+	r := e.rect(el)
+	if e.color != nil {
+		e.t.Color = e.color.At(el)
+	}
+	if e.highlight != nil {
+		e.t.Highlight = e.highlight.At(el)
+	}
+	if d := e.t.Duration(); d > 0 {
+		el %= d
+	}
+	e.t.Draw(insta.NewView(dst, r), el)
+}
+
+type imageElement struct {
+	x, y *Value
+	img  image.Image
+}
+
+func (e *imageElement) draw(dst *image.RGBA, el time.Duration) {
+	p := image.Pt(e.x.Int(el), e.y.Int(el))
+	draw.Draw(dst, e.img.Bounds().Add(p), e.img, image.ZP, draw.Over)
+}
+
+type gifElement struct {
+	x, y *Value
+	clip *anim.Clip
+}
+
+func (e *gifElement) draw(dst *image.RGBA, el time.Duration) {
+	i, _ := e.clip.FrameAt(el)
+	f := e.clip.Frames[i].Image
+	p := image.Pt(e.x.Int(el), e.y.Int(el))
+	draw.Draw(dst, f.Bounds().Add(p), f, image.ZP, draw.Over)
+}
+
+type shapeElement struct {
+	kind       string
+	x, y, w, h *Value
+	x2, y2     *Value
+	radius     *Value
+	color      *ColorValue
+	fill       bool
+}
+
+func (e *shapeElement) draw(dst *image.RGBA, el time.Duration) {
+	c := e.color.At(el)
+	x, y := e.x.At(el), e.y.At(el)
+	switch e.kind {
+	case "rect":
+		r := image.Rect(e.x.Int(el), e.y.Int(el), e.x.Int(el)+e.w.Int(el), e.y.Int(el)+e.h.Int(el))
+		if e.fill {
+			gfx.FillRect(dst, r, c)
+		} else {
+			gfx.Rect(dst, r, c)
+		}
+	case "circle":
+		if e.fill {
+			gfx.FillCircle(dst, e.x.Int(el), e.y.Int(el), e.radius.Int(el), c)
+		} else {
+			gfx.Circle(dst, e.x.Int(el), e.y.Int(el), e.radius.Int(el), c)
+		}
+	case "line":
+		gfx.LineAA(dst, x, y, e.x2.At(el), e.y2.At(el), c)
+	}
+}
+
+var elementTypes = []string{"rainbow", "stars", "life", "clock", "text", "image", "gif", "rect", "circle", "line"}
+
+func (c *compiler) element(path string, raw json.RawMessage) *placed {
+	o := c.obj(path, raw)
+	o.required("type")
+	p := &placed{
+		start:   o.dur("start", 0),
+		end:     o.dur("end", -1),
+		opacity: o.value("opacity", 1),
+	}
+	if p.end >= 0 && p.end <= p.start {
+		c.errorf(o.sub("end"), "end must be after start")
+	}
+
+	switch typ := o.str("type", ""); typ {
+	case "rainbow":
+		p.e = &modeElement{box: o.box(), newMode: func() insta.Mode { return insta.NewRainbow() }}
+	case "stars":
+		p.e = &modeElement{box: o.box(), newMode: func() insta.Mode { return insta.NewStarfield() }}
+	case "life":
+		p.e = &modeElement{box: o.box(), newMode: func() insta.Mode { return life.NewMode() }}
+	case "clock":
+		face, err := clock.ParseFace(o.str("face", "digital"))
+		if err != nil {
+			c.errorf(o.sub("face"), "%v", err)
+		}
+		seconds, date := o.boolean("seconds"), o.boolean("date")
+		p.e = &modeElement{box: o.box(), newMode: func() insta.Mode { return clock.NewClock(face, seconds, date) }}
+	case "text":
+		o.required("text")
+		effects, err := insta.ParseTextEffects(o.str("effects", "scroll"))
+		if err != nil {
+			c.errorf(o.sub("effects"), "%v", err)
+		}
+		t := insta.NewText(o.str("text", ""), effects)
+		t.ScrollSpeed = o.num("speed", t.ScrollSpeed)
+		e := &textElement{box: o.box(), t: t}
+		if o.has("color") {
+			e.color = o.color("color", t.Color)
+		}
+		if o.has("highlight") {
+			e.highlight = o.color("highlight", t.Highlight)
+		}
+		t.Width = e.w.Int(0)
+		p.e = e
+	case "image":
+		o.required("src")
+		e := &imageElement{x: o.value("x", 0), y: o.value("y", 0)}
+		w, h := o.num("width", 0), o.num("height", 0)
+		f := c.filter(o)
+		if img := c.loadImage(o, "src"); img != nil {
+			if w > 0 || h > 0 {
+				img = resample.Resize(img, int(w), int(h), f)
+			}
+			e.img = img
+		}
+		p.e = e
+	case "gif":
+		o.required("src")
+		e := &gifElement{x: o.value("x", 0), y: o.value("y", 0)}
+		w, h := o.num("width", 0), o.num("height", 0)
+		c.loadGif(o, "src", e, int(w), int(h), c.filter(o))
+		p.e = e
+	case "rect", "circle", "line":
+		p.e = &shapeElement{
+			kind:   typ,
+			x:      o.value("x", 0),
+			y:      o.value("y", 0),
+			w:      o.value("width", 0),
+			h:      o.value("height", 0),
+			x2:     o.value("x2", 0),
+			y2:     o.value("y2", 0),
+			radius: o.value("radius", 0),
+			color:  o.color("color", color.RGBA{255, 255, 255, 255}),
+			fill:   o.boolean("fill"),
+		}
+	case "":
+	default:
+		c.errorf(o.sub("type"), "unknown type %q, expected one of %s", typ, strings.Join(elementTypes, ", "))
+	}
+	o.done()
+	if p.e == nil {
+		return nil
+	}
+	return p
+}
+
+func (c *compiler) open(o *obj, key string) *os.File {
+	src := o.str(key, "")
+	if src == "" {
+		return nil
+	}
+	if !filepath.IsAbs(src) {
+		src = filepath.Join(c.dir, src)
+	}
+	f, err := os.Open(src)
+	if err != nil {
+		c.errorf(o.sub(key), "%v", err)
+		return nil
+	}
+	return f
+}
+
+// filter returns the resample filter of the optional filter property.
+func (c *compiler) filter(o *obj) resample.Filter {
+	f, err := resample.ParseFilter(o.str("filter", "auto"))
+	if err != nil {
+		c.errorf(o.sub("filter"), "%v", err)
+	}
+	return f
+}
+
+func (c *compiler) loadImage(o *obj, key string) image.Image {
+	f := c.open(o, key)
+	if f == nil {
+		return nil
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		c.errorf(o.sub(key), "%v", err)
+		return nil
+	}
+	return img
+}
+
+// loadGif decodes the composited frames of the gif.
+func (c *compiler) loadGif(o *obj, key string, e *gifElement, w, h int, filter resample.Filter) {
+	f := c.open(o, key)
+	if f == nil {
+		return
+	}
+	defer f.Close()
+	clip, err := anim.DecodeGIF(f)
+	if err != nil {
+		c.errorf(o.sub(key), "%v", err)
+		return
+	}
+	if len(clip.Frames) == 0 {
+		c.errorf(o.sub(key), "gif without frames")
+		return
+	}
+	if w > 0 || h > 0 {
+		clip = clip.Resize(w, h, filter)
+	}
+	e.clip = clip
+}
+
+func (c *compiler) layer(path string, raw json.RawMessage) *sceneLayer {
+	o := c.obj(path, raw)
+	o.required("elements")
+	l := newLayer(o.str("name", ""))
+	l.opacity = o.value("opacity", 1)
+	if name := o.str("blend", ""); name != "" {
+		m, err := layer.ParseBlendMode(name)
+		if err != nil {
+			c.errorf(o.sub("blend"), "%v", err)
+		}
+		l.Mode = m
+	}
+	for i, r := range o.list("elements") {
+		if e := c.element(fmt.Sprintf("%s[%d]", o.sub("elements"), i), r); e != nil {
+			l.elements = append(l.elements, e)
+		}
+	}
+	o.done()
+	return l
+}
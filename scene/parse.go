@@ -0,0 +1,361 @@
+package scene
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/tween"
+)
+
+// Error is a problem in a scene file.
+type Error struct {
+	Line, Col int
+	// Path is the property with the problem, e.g. layers[0].elements[2].x
+	Path string
+	Msg  string
+}
+
+func (e *Error) Error() string {
+	if e.Path == "" {
+		return fmt.Sprintf("%d:%d: %s", e.Line, e.Col, e.Msg)
+	}
+	return fmt.Sprintf("%d:%d: %s: %s", e.Line, e.Col, e.Path, e.Msg)
+}
+
+// ErrorList are all problems of a scene file, sorted by line.
+type ErrorList []*Error
+
+func (l ErrorList) Error() string {
+	var msgs []string
+	for _, e := range l {
+		msgs = append(msgs, e.Error())
+	}
+	return strings.Join(msgs, "\n")
+}
+
+// lineCol returns the 1-based line and column of offset in data.
+func lineCol(data []byte, offset int) (int, int) {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	line := bytes.Count(data[:offset], []byte("\n")) + 1
+	col := offset - bytes.LastIndexByte(data[:offset], '\n')
+	return line, col
+}
+
+func skipSpace(data []byte, off int) int {
+	for off < len(data) && strings.IndexByte(" \t\r\n,:", data[off]) >= 0 {
+		off++
+	}
+	return off
+}
+
+// positions returns the offsets of all properties and array elements in
+// the JSON data, by their path.
+func positions(data []byte) map[string]int {
+	pos := map[string]int{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	var walk func(path string) error
+	walk = func(path string) error {
+		if _, ok := pos[path]; !ok {
+			pos[path] = skipSpace(data, int(dec.InputOffset()))
+		}
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t {
+		case json.Delim('{'):
+			for dec.More() {
+				off := skipSpace(data, int(dec.InputOffset()))
+				k, err := dec.Token()
+				if err != nil {
+					return err
+				}
+				p := fmt.Sprint(k)
+				if path != "" {
+					p = path + "." + p
+				}
+				pos[p] = off
+				if err := walk(p); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		case json.Delim('['):
+			for i := 0; dec.More(); i++ {
+				if err := walk(fmt.Sprintf("%s[%d]", path, i)); err != nil {
+					return err
+				}
+			}
+			_, err = dec.Token()
+		}
+		return err
+	}
+	walk("")
+	return pos
+}
+
+// compiler collects errors while the raw JSON is turned into a scene.
+type compiler struct {
+	data []byte
+	pos  map[string]int
+	errs ErrorList
+	dir  string
+}
+
+func (c *compiler) errorf(path string, format string, args ...interface{}) {
+	// use the position of the closest parent that is known
+	p := path
+	off, ok := c.pos[p]
+	for !ok && p != "" {
+		if i := strings.LastIndexAny(p, ".["); i >= 0 {
+			p = p[:i]
+		} else {
+			p = ""
+		}
+		off, ok = c.pos[p]
+	}
+	line, col := lineCol(c.data, off)
+	c.errs = append(c.errs, &Error{Line: line, Col: col, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+// jsonError converts errors of encoding/json to an Error with position.
+func (c *compiler) jsonError(err error) {
+	off := 0
+	switch e := err.(type) {
+	case *json.SyntaxError:
+		off = int(e.Offset)
+	case *json.UnmarshalTypeError:
+		off = int(e.Offset)
+	}
+	line, col := lineCol(c.data, off)
+	c.errs = append(c.errs, &Error{Line: line, Col: col, Msg: err.Error()})
+}
+
+// obj is a JSON object that is read property by property. Properties that
+// are not read are reported as unknown by done.
+type obj struct {
+	c    *compiler
+	path string
+	m    map[string]json.RawMessage
+	used map[string]bool
+}
+
+func (c *compiler) obj(path string, raw json.RawMessage) *obj {
+	o := &obj{c: c, path: path, used: map[string]bool{}}
+	if err := json.Unmarshal(raw, &o.m); err != nil {
+		c.errorf(path, "expected object")
+	}
+	return o
+}
+
+func (o *obj) sub(key string) string {
+	if o.path == "" {
+		return key
+	}
+	return o.path + "." + key
+}
+
+func (o *obj) has(key string) bool {
+	_, ok := o.m[key]
+	return ok
+}
+
+func (o *obj) raw(key string) (json.RawMessage, bool) {
+	o.used[key] = true
+	r, ok := o.m[key]
+	return r, ok
+}
+
+// decode decodes the property into v. It returns false if the property is
+// missing or invalid.
+func (o *obj) decode(key string, v interface{}, what string) bool {
+	r, ok := o.raw(key)
+	if !ok {
+		return false
+	}
+	if err := json.Unmarshal(r, v); err != nil {
+		o.c.errorf(o.sub(key), "expected %s", what)
+		return false
+	}
+	return true
+}
+
+func (o *obj) str(key, def string) string {
+	v := def
+	o.decode(key, &v, "string")
+	return v
+}
+
+func (o *obj) num(key string, def float64) float64 {
+	v := def
+	o.decode(key, &v, "number")
+	return v
+}
+
+func (o *obj) boolean(key string) bool {
+	var v bool
+	o.decode(key, &v, "true or false")
+	return v
+}
+
+func (o *obj) required(key string) {
+	if !o.has(key) {
+		o.c.errorf(o.path, "missing property %q", key)
+	}
+}
+
+func (o *obj) list(key string) []json.RawMessage {
+	var l []json.RawMessage
+	o.decode(key, &l, "list")
+	return l
+}
+
+func (o *obj) dur(key string, def time.Duration) time.Duration {
+	r, ok := o.raw(key)
+	if !ok {
+		return def
+	}
+	d, err := parseDuration(r)
+	if err != nil {
+		o.c.errorf(o.sub(key), "%v", err)
+		return def
+	}
+	return d
+}
+
+// parseDuration parses a duration like "1.5s" or a number of seconds.
+func parseDuration(r json.RawMessage) (time.Duration, error) {
+	var sec float64
+	if err := json.Unmarshal(r, &sec); err == nil {
+		return time.Duration(sec * float64(time.Second)), nil
+	}
+	var s string
+	if err := json.Unmarshal(r, &s); err == nil {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("invalid duration %s, expected seconds or a duration like \"1.5s\"", r)
+}
+
+// done reports all properties that were not read.
+func (o *obj) done() {
+	var unknown []string
+	for k := range o.m {
+		if !o.used[k] {
+			unknown = append(unknown, k)
+		}
+	}
+	sort.Strings(unknown)
+	for _, k := range unknown {
+		o.c.errorf(o.sub(k), "unknown property")
+	}
+}
+
+// keys reads a list of keyframes like [{"t": "1s", "v": 10, "ease":
+// "outQuad"}] and calls value for each v.
+func (o *obj) keys(key string, value func(path string, r json.RawMessage) bool) []keyframe {
+	var ks []keyframe
+	for i, r := range o.list(key) {
+		k := o.c.obj(fmt.Sprintf("%s[%d]", o.sub(key), i), r)
+		kf := keyframe{ease: tween.Linear}
+		k.required("t")
+		k.required("v")
+		kf.t = k.dur("t", 0)
+		if len(ks) > 0 && kf.t < ks[len(ks)-1].t {
+			o.c.errorf(k.path, "keyframes must be sorted by t")
+		}
+		// Skip keyframes without a usable v so that ks and the values
+		// collected by value stay in step.
+		if r, ok := k.raw("v"); !ok || !value(k.sub("v"), r) {
+			continue
+		}
+		if name := k.str("ease", ""); name != "" {
+			e, err := tween.ParseEase(name)
+			if err != nil {
+				o.c.errorf(k.sub("ease"), "%v", err)
+			} else {
+				kf.ease = e
+			}
+		}
+		k.done()
+		ks = append(ks, kf)
+	}
+	return ks
+}
+
+// value reads a number or a list of keyframes.
+func (o *obj) value(key string, def float64) *Value {
+	v := &Value{v: def}
+	r, ok := o.raw(key)
+	if !ok {
+		return v
+	}
+	if json.Unmarshal(r, &v.v) == nil {
+		return v
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(r), []byte("[")) {
+		o.c.errorf(o.sub(key), "expected number or list of keyframes")
+		return v
+	}
+	var vals []float64
+	ks := o.keys(key, func(path string, r json.RawMessage) bool {
+		var f float64
+		if err := json.Unmarshal(r, &f); err != nil {
+			o.c.errorf(path, "expected number")
+			return false
+		}
+		vals = append(vals, f)
+		return true
+	})
+	for i := range ks {
+		ks[i].v = vals[i]
+	}
+	v.keys = ks
+	return v
+}
+
+// color reads a color like "#ff8000" or a list of keyframes.
+func (o *obj) color(key string, def color.RGBA) *ColorValue {
+	v := &ColorValue{c: def}
+	r, ok := o.raw(key)
+	if !ok {
+		return v
+	}
+	parse := func(path string, r json.RawMessage) (color.RGBA, bool) {
+		var s string
+		if err := json.Unmarshal(r, &s); err != nil {
+			o.c.errorf(path, "expected color like \"#ff8000\"")
+			return color.RGBA{}, false
+		}
+		c, err := insta.ParseHexColor(s)
+		if err != nil {
+			o.c.errorf(path, "%v", err)
+			return color.RGBA{}, false
+		}
+		return c, true
+	}
+	if !bytes.HasPrefix(bytes.TrimSpace(r), []byte("[")) {
+		v.c, _ = parse(o.sub(key), r)
+		return v
+	}
+	var cols []color.RGBA
+	ks := o.keys(key, func(path string, r json.RawMessage) bool {
+		c, ok := parse(path, r)
+		if ok {
+			cols = append(cols, c)
+		}
+		return ok
+	})
+	v.keys = ks
+	v.cols = cols
+	return v
+}
@@ -0,0 +1,197 @@
+// Package scene plays scenes that are described in JSON files, so that
+// content can be made without writing Go.
+//
+// A scene has a duration and a list of layers. Each layer has elements
+// like images, gifs, text, shapes and built-in effects:
+//
+//	{
+//	  "duration": "10s",
+//	  "loop": true,
+//	  "layers": [
+//	    {"elements": [{"type": "stars"}]},
+//	    {"opacity": 0.8, "blend": "add", "elements": [
+//	      {"type": "text", "text": "Hello *World*", "effects": "scroll,wave"},
+//	      {"type": "circle", "fill": true, "radius": 4, "color": "#ff8000",
+//	       "x": [{"t": 0, "v": 0}, {"t": "5s", "v": 54, "ease": "inOutQuad"}],
+//	       "y": 18, "start": "2s", "end": "7s"}
+//	    ]}
+//	  ]
+//	}
+//
+// All elements have the optional properties start, end and opacity. The
+// types and their properties are:
+//
+//	rainbow, stars, life  x, y, width, height
+//	clock                 x, y, width, height, face, seconds, date
+//	text                  x, y, width, height, text, effects, color, highlight, speed
+//	image, gif            x, y, src, width, height
+//	rect                  x, y, width, height, color, fill
+//	circle                x, y (center), radius, color, fill
+//	line                  x, y, x2, y2, color
+//
+// Layers have the optional properties name, opacity and blend (normal,
+// add, multiply or screen).
+//
+// Numbers and colors are either constant or a list of keyframes with the
+// time t, the value v and an optional easing curve from the previous
+// keyframe (see tween.ParseEase). Times are seconds or durations like
+// "1.5s".
+package scene
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/layer"
+)
+
+// Scene is a parsed scene. It is an insta.Mode.
+type Scene struct {
+	Duration time.Duration
+	// Loop restarts the scene after Duration, when it is drawn longer.
+	Loop bool
+
+	background *ColorValue
+	layers     []*sceneLayer
+	scratch    *image.RGBA
+}
+
+type sceneLayer struct {
+	*layer.Layer
+	opacity  *Value
+	elements []*placed
+}
+
+func newLayer(name string) *sceneLayer {
+	return &sceneLayer{Layer: &layer.Layer{
+		RGBA:    image.NewRGBA(image.Rect(0, 0, insta.ScreenWidth, insta.ScreenHeight)),
+		Name:    name,
+		Opacity: 1,
+		Visible: true,
+	}}
+}
+
+// Load reads and parses the scene file. Paths of images are relative to
+// the directory of the file. Problems are returned as ErrorList.
+func Load(fname string) (*Scene, error) {
+	data, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data, filepath.Dir(fname))
+}
+
+// Parse parses a scene. Paths of images are relative to dir.
+func Parse(data []byte, dir string) (*Scene, error) {
+	c := &compiler{data: data, dir: dir}
+	var raw json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		c.jsonError(err)
+		return nil, c.errs
+	}
+	c.pos = positions(data)
+
+	s := &Scene{scratch: image.NewRGBA(image.Rect(0, 0, insta.ScreenWidth, insta.ScreenHeight))}
+	o := c.obj("", raw)
+	o.required("duration")
+	o.required("layers")
+	n := len(c.errs)
+	s.Duration = o.dur("duration", 0)
+	if len(c.errs) == n && o.has("duration") && s.Duration <= 0 {
+		c.errorf("duration", "duration must be positive")
+	}
+	s.Loop = o.boolean("loop")
+	s.background = o.color("background", color.RGBA{0, 0, 0, 255})
+	for i, r := range o.list("layers") {
+		s.layers = append(s.layers, c.layer(fmt.Sprintf("layers[%d]", i), r))
+	}
+	o.done()
+
+	if len(c.errs) > 0 {
+		sort.SliceStable(c.errs, func(i, j int) bool {
+			a, b := c.errs[i], c.errs[j]
+			return a.Line < b.Line || a.Line == b.Line && a.Col < b.Col
+		})
+		return nil, c.errs
+	}
+	return s, nil
+}
+
+// Validate checks the scene file without playing it. All problems are
+// returned with their line.
+func Validate(fname string) ErrorList {
+	_, err := Load(fname)
+	if err == nil {
+		return nil
+	}
+	if l, ok := err.(ErrorList); ok {
+		return l
+	}
+	return ErrorList{{Msg: err.Error()}}
+}
+
+// Draw draws the scene at the time el since its start.
+func (s *Scene) Draw(dst draw.Image, el time.Duration) {
+	if s.Loop && s.Duration > 0 {
+		el %= s.Duration
+	}
+	scr, ok := dst.(*insta.Screen)
+	if !ok {
+		scr = insta.NewScreen()
+	}
+	bg := s.background.At(el)
+	for i := 0; i < len(scr.Pix); i += insta.PixelStride {
+		scr.Pix[i], scr.Pix[i+1], scr.Pix[i+2] = bg.R, bg.G, bg.B
+	}
+
+	for _, l := range s.layers {
+		l.Clear()
+		for _, p := range l.elements {
+			if el < p.start || p.end >= 0 && el >= p.end {
+				continue
+			}
+			op := p.opacity.At(el)
+			if op <= 0 {
+				continue
+			}
+			for i := range s.scratch.Pix {
+				s.scratch.Pix[i] = 0
+			}
+			p.e.draw(s.scratch, el-p.start)
+			mask := image.NewUniform(color.Alpha{uint8(clamp(op, 0, 1) * 255)})
+			draw.DrawMask(l.RGBA, l.Bounds(), s.scratch, image.ZP, mask, image.ZP, draw.Over)
+		}
+		l.Opacity = l.opacity.At(el)
+		l.Composite(scr)
+	}
+
+	if !ok {
+		draw.Draw(dst, dst.Bounds(), scr, image.ZP, draw.Src)
+	}
+}
+
+// Play plays the scene once, or for d if the scene loops.
+func (s *Scene) Play(c insta.Client, d time.Duration) {
+	if !s.Loop || d <= 0 {
+		d = s.Duration
+	}
+	insta.RunMode(c, s, d)
+}
+
+func clamp(v, min, max float64) float64 {
+	if v < min {
+		return min
+	}
+	if v > max {
+		return max
+	}
+	return v
+}
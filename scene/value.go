@@ -0,0 +1,73 @@
+package scene
+
+import (
+	"image/color"
+	"time"
+
+	"github.com/ktt-ol/go-insta/colors"
+	"github.com/ktt-ol/go-insta/tween"
+)
+
+type keyframe struct {
+	t time.Duration
+	v float64
+	// ease is the curve from the previous keyframe to this keyframe
+	ease tween.Ease
+}
+
+// progress returns the keyframes around el and the eased progress
+// between them. Before the first and after the last keyframe, both
+// keyframes are the same.
+func progress(keys []keyframe, el time.Duration) (a, b int, t float64) {
+	if el <= keys[0].t {
+		return 0, 0, 0
+	}
+	for i := 1; i < len(keys); i++ {
+		if el < keys[i].t {
+			k0, k1 := keys[i-1], keys[i]
+			return i - 1, i, k1.ease(float64(el-k0.t) / float64(k1.t-k0.t))
+		}
+	}
+	return len(keys) - 1, len(keys) - 1, 0
+}
+
+// Value is a number that is either constant or animated with keyframes.
+type Value struct {
+	v    float64
+	keys []keyframe
+}
+
+// At returns the value at the time el since the start of the scene.
+func (v *Value) At(el time.Duration) float64 {
+	if len(v.keys) == 0 {
+		return v.v
+	}
+	a, b, t := progress(v.keys, el)
+	return v.keys[a].v + (v.keys[b].v-v.keys[a].v)*t
+}
+
+// Int returns the rounded value at el.
+func (v *Value) Int(el time.Duration) int {
+	f := v.At(el)
+	if f < 0 {
+		return int(f - 0.5)
+	}
+	return int(f + 0.5)
+}
+
+// ColorValue is a color that is either constant or animated with
+// keyframes. Colors are interpolated in linear light.
+type ColorValue struct {
+	c    color.RGBA
+	keys []keyframe
+	cols []color.RGBA
+}
+
+// At returns the color at the time el since the start of the scene.
+func (v *ColorValue) At(el time.Duration) color.RGBA {
+	if len(v.keys) == 0 {
+		return v.c
+	}
+	a, b, t := progress(v.keys, el)
+	return colors.Lerp(v.cols[a], v.cols[b], t)
+}
@@ -5,8 +5,9 @@ import (
 	"image/color"
 	"image/color/palette"
 	"image/draw"
-	"math"
 	"strconv"
+
+	"github.com/ktt-ol/go-insta/colors"
 )
 
 const (
@@ -114,57 +115,18 @@ func (s *Screen) Panel(x, y int) ([486]uint8, [486]uint8) {
 }
 
 func HsvToRgb(h, s, v float64) (r, g, b float64) {
-	var i int
-	var f, p, q, t float64
-
-	if s == 0 {
-		// achromatic (grey)
-		r = v
-		g = v
-		b = v
-		return
-	}
+	return colors.HSVToRGB(h, s, v)
+}
 
-	h = h / 60 // sector 0 to 5
-	i = int(math.Floor(h))
-	f = h - float64(i) // factorial part of h
-	p = v * (1 - s)
-	q = v * (1 - s*f)
-	t = v * (1 - s*(1-f))
-
-	switch i {
-	case 0:
-		r = v
-		g = t
-		b = p
-	case 1:
-		r = q
-		g = v
-		b = p
-	case 2:
-		r = p
-		g = v
-		b = t
-	case 3:
-		r = p
-		g = q
-		b = v
-	case 4:
-		r = t
-		g = p
-		b = v
-	default: // case 5:
-		r = v
-		g = p
-		b = q
-	}
-	return
+// RgbToHsv is the inverse of HsvToRgb.
+func RgbToHsv(r, g, b float64) (h, s, v float64) {
+	return colors.RGBToHSV(r, g, b)
 }
 
 func HsvToColor(h, s, v float64) color.RGBA {
-	r, g, b := HsvToRgb(h, s, v)
-	return color.RGBA{uint8(r * 255), uint8(g * 255), uint8(b * 255), 255}
+	return colors.HSV(h, s, v)
 }
+
 func ScreenToImage(s *Screen) image.Image {
 	dst := image.NewRGBA(s.Bounds())
 	// draw.DrawMask(dst, dst.Bounds(), s, image.ZP, s, image.ZP, draw.Over)
@@ -179,30 +141,34 @@ func ScreenToPalettedImage(s *Screen) *image.Paletted {
 	return dst
 }
 
+// BlendImages returns steps images that fade from a to b. The last image
+// is b. Colors are mixed in linear light.
 func BlendImages(a, b image.Image, steps int) []*image.Paletted {
+	bnds := a.Bounds()
+	src, dst := image.NewRGBA(bnds), image.NewRGBA(bnds)
+	draw.Draw(src, bnds, a, bnds.Min, draw.Src)
+	draw.Draw(dst, bnds, b, b.Bounds().Min, draw.Src)
+	mix := image.NewRGBA(bnds)
 	imgs := make([]*image.Paletted, steps)
 	for i := 0; i < steps; i++ {
-		dst := image.NewPaletted(a.Bounds(), palette.Plan9)
-		draw.Draw(dst, dst.Bounds(), a, image.ZP, draw.Over)
-		alpha := uint8(255.0 / float32(steps) * float32(i+1))
-		draw.DrawMask(dst, dst.Bounds(), b, image.ZP, image.NewUniform(color.Alpha{alpha}), image.ZP, draw.Over)
-		imgs[i] = dst
+		colors.LerpBytes(mix.Pix, src.Pix, dst.Pix, float64(i+1)/float64(steps))
+		img := image.NewPaletted(bnds, palette.Plan9)
+		draw.Draw(img, bnds, mix, bnds.Min, draw.Src)
+		imgs[i] = img
 	}
 	return imgs
 }
 
+// BlendScreens returns steps screens that fade from a to b. The last screen
+// is b. Colors are mixed in linear light.
 func BlendScreens(a, b *Screen, steps int) []*Screen {
-	screens := make([]*Screen, steps)
 	if steps <= 1 {
 		return []*Screen{b}
 	}
+	screens := make([]*Screen, steps)
 	for i := 0; i < steps; i++ {
 		dst := NewScreen()
-
-		t := 1 / float32(steps) * float32(i+1)
-		for i := 0; i < ScreenWidth*ScreenHeight*PixelStride; i++ {
-			dst.Pix[i] = uint8((1-t)*float32(a.Pix[i]) + t*float32(b.Pix[i]))
-		}
+		colors.LerpBytes(dst.Pix, a.Pix, b.Pix, float64(i+1)/float64(steps))
 		screens[i] = dst
 	}
 	return screens
@@ -0,0 +1,239 @@
+package insta
+
+import (
+	"image"
+	"image/draw"
+	"log"
+	"math/rand"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ktt-ol/go-insta/media"
+	"github.com/ktt-ol/go-insta/resample"
+	"github.com/ktt-ol/go-insta/timing"
+	"github.com/ktt-ol/go-insta/tween"
+)
+
+// kenBurnsScale is the resolution of the images during the slideshow in
+// multiples of the screen size. Each screen pixel averages the details of
+// the source at this resolution, also when zoomed in.
+const kenBurnsScale = 6
+
+// kenBurnsMaxPan limits the panning across panoramas, they are cropped to
+// at most this many screen widths or heights.
+const kenBurnsMaxPan = 3
+
+// Slideshow shows the still images of a library with a slow pan and zoom
+// across each image (Ken Burns effect) and crossfades between the images.
+type Slideshow struct {
+	Library *media.Library
+	// Tag selects the images of the library, "" shows all.
+	Tag string
+	// Show is the time of the pan and zoom of each image, Fade the time of
+	// the crossfade to the next image.
+	Show, Fade time.Duration
+	// MaxZoom is the largest zoom into an image, 1 only pans.
+	MaxZoom float64
+	// Captions shows the file names without extension at the bottom.
+	Captions bool
+	Filter   resample.Filter
+}
+
+// NewSlideshow returns a slideshow of lib with default settings.
+func NewSlideshow(lib *media.Library) *Slideshow {
+	return &Slideshow{
+		Library: lib,
+		Show:    8 * time.Second,
+		Fade:    time.Second,
+		MaxZoom: 1.5,
+	}
+}
+
+// Play shows images till d passed, or each image once if d is 0.
+func (s *Slideshow) Play(c Client, d time.Duration) {
+	n := len(s.Library.Items(s.Tag))
+	start := timing.Now()
+	var prev *Screen
+	fps := 25.0
+	for i, failed := 0, 0; failed < n; i++ {
+		if d == 0 && i >= n || d > 0 && timing.Since(start) >= d {
+			return
+		}
+		fname, ok := s.Library.Next(s.Tag)
+		if !ok {
+			return
+		}
+		kb, err := s.load(fname)
+		if err != nil {
+			log.Printf("%s: %s", fname, err)
+			failed++
+			continue
+		}
+		failed = 0
+
+		scr := NewScreen()
+		kb.draw(scr, 0, 0)
+		if prev != nil {
+			for _, b := range BlendScreens(prev, scr, int(s.Fade.Seconds()*fps)) {
+				c.SetScreen(b)
+			}
+		}
+
+		shown := timing.Now()
+		frames := 0
+		for el := time.Duration(0); el < s.Show; el = timing.Since(shown) {
+			scr = NewScreen()
+			kb.draw(scr, float64(el)/float64(s.Show), el)
+			c.SetScreen(scr)
+			frames++
+		}
+		// the client limits the frame rate, measure it for the crossfade
+		if sec := timing.Since(shown).Seconds(); frames > 1 && sec > 0 {
+			fps = float64(frames) / sec
+		}
+		prev = scr
+	}
+}
+
+// load returns the pan and zoom of a random motion across the image.
+func (s *Slideshow) load(fname string) (*kenBurns, error) {
+	img, err := decodeImage(fname)
+	if err != nil {
+		return nil, err
+	}
+	// scale to cover the work size, keeping the full image for panning
+	img = cropAspect(img, kenBurnsMaxPan)
+	b := img.Bounds()
+	ww, wh := ScreenWidth*kenBurnsScale, ScreenHeight*kenBurnsScale
+	sw, sh := ww, b.Dy()*ww/b.Dx()
+	if sh < wh {
+		sw, sh = b.Dx()*wh/b.Dy(), wh
+	}
+	k := &kenBurns{work: HDRFromImage(resample.Resize(img, sw, sh, s.Filter))}
+
+	zoom := 1 + (s.MaxZoom-1)*(0.6+0.4*rand.Float64())
+	if s.MaxZoom < 1 {
+		zoom = 1
+	}
+	k.from, k.to = k.randomView(1), k.randomView(zoom)
+	if rand.Intn(2) == 0 {
+		k.from, k.to = k.to, k.from
+	}
+
+	if s.Captions {
+		k.caption = caption(fname, s.Show)
+	}
+	return k, nil
+}
+
+// cropAspect cuts a random part of very wide or tall images (panoramas),
+// so that they are at most max times as wide or tall as the screen when
+// they cover it.
+func cropAspect(img image.Image, max int) image.Image {
+	b := img.Bounds()
+	r := b
+	if w := b.Dy() * ScreenWidth * max / ScreenHeight; b.Dx() > w {
+		r.Min.X += rand.Intn(b.Dx() - w + 1)
+		r.Max.X = r.Min.X + w
+	} else if h := b.Dx() * ScreenHeight * max / ScreenWidth; b.Dy() > h {
+		r.Min.Y += rand.Intn(b.Dy() - h + 1)
+		r.Max.Y = r.Min.Y + h
+	}
+	if r == b {
+		return img
+	}
+	if sub, ok := img.(interface {
+		SubImage(image.Rectangle) image.Image
+	}); ok {
+		return sub.SubImage(r)
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, r.Dx(), r.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, r.Min, draw.Src)
+	return dst
+}
+
+type view struct {
+	x, y, zoom float64
+}
+
+type kenBurns struct {
+	work     *HDR
+	from, to view
+	caption  *Text
+}
+
+// randomView returns a random center of the view for the zoom.
+func (k *kenBurns) randomView(zoom float64) view {
+	w, h := k.size(zoom)
+	return view{
+		x:    w/2 + rand.Float64()*(float64(k.work.W)-w),
+		y:    h/2 + rand.Float64()*(float64(k.work.H)-h),
+		zoom: zoom,
+	}
+}
+
+// size returns the size of the view in work pixels.
+func (k *kenBurns) size(zoom float64) (float64, float64) {
+	return ScreenWidth * kenBurnsScale / zoom, ScreenHeight * kenBurnsScale / zoom
+}
+
+// draw draws the view at t from 0 to 1 of the motion. Each screen pixel
+// averages 4x4 samples of its area.
+func (k *kenBurns) draw(dst *Screen, t float64, el time.Duration) {
+	e := tween.InOutSine(t)
+	v := view{
+		x:    k.from.x + (k.to.x-k.from.x)*e,
+		y:    k.from.y + (k.to.y-k.from.y)*e,
+		zoom: k.from.zoom + (k.to.zoom-k.from.zoom)*e,
+	}
+	w, h := k.size(v.zoom)
+	x0, y0 := v.x-w/2, v.y-h/2
+	px, py := w/ScreenWidth, h/ScreenHeight
+
+	const n = 4
+	frame := NewHDR(ScreenWidth, ScreenHeight)
+	for y := 0; y < ScreenHeight; y++ {
+		for x := 0; x < ScreenWidth; x++ {
+			var r, g, b float32
+			for sy := 0; sy < n; sy++ {
+				for sx := 0; sx < n; sx++ {
+					sr, sg, sb := k.work.Sample(
+						x0+(float64(x)+(float64(sx)+0.5)/n)*px,
+						y0+(float64(y)+(float64(sy)+0.5)/n)*py)
+					r, g, b = r+sr, g+sg, b+sb
+				}
+			}
+			frame.Add(x, y, r/(n*n), g/(n*n), b/(n*n))
+		}
+	}
+	frame.ToScreen(dst, 1, ToneClamp)
+
+	if k.caption != nil {
+		// darken the bottom rows behind the caption
+		band := image.Rect(0, ScreenHeight-7, ScreenWidth, ScreenHeight)
+		for y := band.Min.Y; y < band.Max.Y; y++ {
+			for x := band.Min.X; x < band.Max.X; x++ {
+				o := (y*ScreenWidth + x) * 3
+				for i := 0; i < 3; i++ {
+					dst.Pix[o+i] /= 3
+				}
+			}
+		}
+		k.caption.Draw(dst.View(band), el)
+	}
+}
+
+// caption returns the file name of fname without extension as text, with
+// spaces for underscores and dashes. Long captions scroll.
+func caption(fname string, d time.Duration) *Text {
+	name := strings.TrimSuffix(filepath.Base(fname), filepath.Ext(fname))
+	name = strings.NewReplacer("_", " ", "-", " ").Replace(name)
+	t := NewText(name, 0)
+	t.Face = Face3x5
+	t.Hold = d
+	if t.lineWidth(0) > ScreenWidth {
+		t.Effects = TextScroll
+	}
+	return t
+}
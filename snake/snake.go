@@ -1,21 +1,51 @@
 package snake
 
 import (
+	"bytes"
+	_ "embed"
 	"fmt"
 	"image"
 	"image/color"
 	"image/draw"
+	"image/png"
 	"math/rand"
 	"time"
 
 	"golang.org/x/image/font/basicfont"
 
 	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/colors"
+	"github.com/ktt-ol/go-insta/sprite"
+	"github.com/ktt-ol/go-insta/theme"
+	"github.com/ktt-ol/go-insta/timing"
 
 	"golang.org/x/image/font"
 	"golang.org/x/image/math/fixed"
 )
 
+var (
+	//go:embed sprites.png
+	spritesPNG []byte
+	//go:embed sprites.json
+	spritesJSON []byte
+
+	sprites *sprite.Sheet
+	// white parts of the sprites are drawn in the color of the player or
+	// fruit
+	spriteKey = color.RGBA{255, 255, 255, 255}
+)
+
+func init() {
+	img, err := png.Decode(bytes.NewReader(spritesPNG))
+	if err != nil {
+		panic(err)
+	}
+	sprites, err = sprite.Parse(spritesJSON, img)
+	if err != nil {
+		panic(err)
+	}
+}
+
 type Piece struct {
 	X, Y  int
 	Set   bool
@@ -87,6 +117,7 @@ type Game struct {
 	Players       []*Player
 	tickDur       time.Duration
 	ExitAfterIdle time.Duration
+	start         time.Time
 }
 
 type GameStatus int
@@ -102,21 +133,23 @@ func NewGame(w, h int, exitAfterIdle time.Duration) *Game {
 	for i := range f {
 		f[i] = make([]Cell, w)
 	}
+	th := theme.Current()
 	g := &Game{
 		Field:         f,
 		Width:         w,
 		Height:        h,
 		ExitAfterIdle: exitAfterIdle,
+		start:         timing.Now(),
 		Players: []*Player{
 			&Player{
 				Head: Piece{
-					Color: color.RGBA{255, 255, 0, 255},
+					Color: th.Player(0),
 					Set:   true,
 				},
 			},
 			&Player{
 				Head: Piece{
-					Color: color.RGBA{255, 0, 255, 255},
+					Color: th.Player(1),
 					Set:   true,
 				},
 			},
@@ -139,22 +172,22 @@ func (g *Game) Init() {
 		dir = Right
 	}
 	p := g.Players[0]
-	p.Head.X = insta.ScreenWidth / 2
-	p.Head.Y = insta.ScreenHeight / 4
+	p.Head.X = g.Width / 2
+	p.Head.Y = g.Height / 4
 	p.Dir = dir
 	p.Length = 10
 	p.Tail = nil
 	p.Score = 0
-	p.idleTime = time.Now().Add(g.ExitAfterIdle)
+	p.idleTime = timing.Now().Add(g.ExitAfterIdle)
 
 	p = g.Players[1]
-	p.Head.X = insta.ScreenWidth / 2
-	p.Head.Y = insta.ScreenHeight - insta.ScreenHeight/4
+	p.Head.X = g.Width / 2
+	p.Head.Y = g.Height - g.Height/4
 	p.Dir = dir
 	p.Length = 10
 	p.Tail = nil
 	p.Score = 0
-	p.idleTime = time.Now().Add(g.ExitAfterIdle)
+	p.idleTime = timing.Now().Add(g.ExitAfterIdle)
 
 	g.tickDur = time.Millisecond * 50
 
@@ -188,7 +221,7 @@ func (g *Game) move(p *Player) {
 }
 
 func (g *Game) Step(pads []insta.Pad) GameStatus {
-	time.Sleep(g.tickDur)
+	timing.Sleep(g.tickDur)
 
 	activePlayer := false
 	for i, p := range g.Players {
@@ -217,8 +250,8 @@ func (g *Game) Step(pads []insta.Pad) GameStatus {
 			moved = true
 		}
 		if moved {
-			p.idleTime = time.Now().Add(g.ExitAfterIdle)
-		} else if p.idleTime.Before(time.Now()) {
+			p.idleTime = timing.Now().Add(g.ExitAfterIdle)
+		} else if p.idleTime.Before(timing.Now()) {
 			p.idleTime = time.Time{}
 			for _, p := range p.Tail {
 				g.Field[p.Y][p.X].Snake = false
@@ -292,25 +325,62 @@ func (g *Game) Step(pads []insta.Pad) GameStatus {
 	return Running
 }
 
+// Play runs games till all players were idle for ExitAfterIdle. The score
+// is shown after each game.
+func (g *Game) Play(c insta.Client, pads func() []insta.Pad) {
+	for {
+		s := insta.NewScreen()
+		g.Init()
+		for {
+			status := g.Step(pads())
+			g.Paint(s)
+			c.SetScreenImmediate(s)
+			if status == End {
+				timing.Sleep(250 * time.Millisecond)
+				// wait to prevent score screen from being overdrawn
+				// by last game screen
+				g.PaintScore(s)
+				c.SetScreenImmediate(s)
+				timing.Sleep(3 * time.Second)
+				break
+			}
+			if status == Exit {
+				return
+			}
+		}
+	}
+}
+
 func (g *Game) SpawnFruit() {
 	for {
 		y := rand.Intn(len(g.Field))
 		x := rand.Intn(len(g.Field[0]))
 		if !g.Field[y][x].Snake && !g.Field[y][x].Fruit {
 			g.Field[y][x].Fruit = true
-			g.Field[y][x].Color = insta.HsvToColor(rand.Float64()*360, 0.8, 0.5)
+			// fruits are darker than the snakes
+			c := theme.Current().Color(rand.Float64())
+			g.Field[y][x].Color = colors.Lerp(color.RGBA{0, 0, 0, 255}, c, 0.25)
 			return
 		}
 	}
 }
 
 func (g *Game) Paint(img draw.Image) {
+	el := timing.Since(g.start)
+	for y := range g.Field {
+		for x := range g.Field[y] {
+			img.Set(x, y, color.RGBA{0, 0, 0, 0})
+		}
+	}
+
+	fruit := sprites.Animations["fruit"]
 	for y := range g.Field {
 		for x := range g.Field[y] {
 			if g.Field[y][x].Fruit {
-				img.Set(x, y, g.Field[y][x].Color)
-			} else {
-				img.Set(x, y, color.RGBA{0, 0, 0, 0})
+				// sprites are centered on their cell
+				sprites.DrawFrame(img, image.Pt(x-1, y-1), sprites.FrameAt(fruit, el), &sprite.Options{
+					Swap: map[color.RGBA]color.RGBA{spriteKey: g.Field[y][x].Color},
+				})
 			}
 		}
 	}
@@ -320,6 +390,26 @@ func (g *Game) Paint(img draw.Image) {
 			img.Set(t.X, t.Y, t.Color)
 		}
 	}
+
+	for _, p := range g.Players {
+		if len(p.Tail) == 0 {
+			continue
+		}
+		anim, flip := "head", sprite.Flip(0)
+		switch p.Dir {
+		case Left:
+			flip = sprite.FlipH
+		case Up:
+			anim = "head-up"
+		case Down:
+			anim, flip = "head-up", sprite.FlipV
+		}
+		a := sprites.Animations[anim]
+		sprites.DrawFrame(img, image.Pt(p.Head.X-1, p.Head.Y-1), sprites.FrameAt(a, el), &sprite.Options{
+			Flip: flip,
+			Swap: map[color.RGBA]color.RGBA{spriteKey: p.Head.Color},
+		})
+	}
 }
 
 func (g *Game) PaintScore(img draw.Image) {
@@ -338,8 +428,10 @@ func (g *Game) PaintScore(img draw.Image) {
 		Src:  image.White,
 		Face: basicfont.Face7x13,
 	}
-	d.Dot = fixed.P(insta.ScreenWidth/2-(fontWidth*len(scores)/2), insta.ScreenHeight/2-fontHeight/2)
+	b := img.Bounds()
+	cx, cy := b.Min.X+b.Dx()/2, b.Min.Y+b.Dy()/2
+	d.Dot = fixed.P(cx-(fontWidth*len(scores)/2), cy-fontHeight/2)
 	d.DrawString(scores)
-	d.Dot = fixed.P(insta.ScreenWidth/2-(fontWidth*len(totalScores)/2), insta.ScreenHeight/2+fontHeight/2)
+	d.Dot = fixed.P(cx-(fontWidth*len(totalScores)/2), cy+fontHeight/2)
 	d.DrawString(totalScores)
 }
@@ -0,0 +1,262 @@
+// Package sprite loads sprite sheets and draws animated sprites.
+//
+// A sheet is a PNG image with a JSON description of the frames and named
+// animations:
+//
+//	{
+//	  "image": "sprites.png",
+//	  "frames": [{"x": 0, "y": 0, "w": 3, "h": 3, "duration": 200}],
+//	  "animations": {"head": {"frames": [0, 1], "loop": true}}
+//	}
+//
+// Instead of frames, "grid": {"w": 3, "h": 3, "duration": 200} splits the
+// whole image into frames of the same size, row by row. Durations are in
+// milliseconds. Without animations, all frames are the animation
+// "default".
+package sprite
+
+import (
+	"encoding/json"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/png"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ktt-ol/go-insta/gfx"
+)
+
+// Frame is a part of the sheet image.
+type Frame struct {
+	Rect     image.Rectangle
+	Duration time.Duration
+}
+
+// Animation is a sequence of frames.
+type Animation struct {
+	Name   string
+	Frames []int
+	Loop   bool
+}
+
+// Sheet is an image with frames and animations.
+type Sheet struct {
+	Image      *image.NRGBA
+	Frames     []Frame
+	Animations map[string]*Animation
+}
+
+type sheetDesc struct {
+	Image  string `json:"image"`
+	Frames []struct {
+		X, Y, W, H int
+		Duration   int `json:"duration"`
+	} `json:"frames"`
+	Grid *struct {
+		W, H     int
+		Duration int `json:"duration"`
+	} `json:"grid"`
+	Animations map[string]struct {
+		Frames []int `json:"frames"`
+		Loop   bool  `json:"loop"`
+	} `json:"animations"`
+}
+
+// Load loads the sheet description and the image it refers to. The image
+// path is relative to the description.
+func Load(fname string) (*Sheet, error) {
+	desc, err := ioutil.ReadFile(fname)
+	if err != nil {
+		return nil, err
+	}
+	var d sheetDesc
+	if err := json.Unmarshal(desc, &d); err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	if d.Image == "" {
+		return nil, fmt.Errorf("%s: missing image", fname)
+	}
+	f, err := os.Open(filepath.Join(filepath.Dir(fname), d.Image))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	img, _, err := image.Decode(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", d.Image, err)
+	}
+	s, err := Parse(desc, img)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	return s, nil
+}
+
+// Parse returns the sheet for the description and the image. The image
+// entry of the description is ignored.
+func Parse(desc []byte, img image.Image) (*Sheet, error) {
+	var d sheetDesc
+	if err := json.Unmarshal(desc, &d); err != nil {
+		return nil, err
+	}
+	b := img.Bounds()
+	s := &Sheet{
+		Image:      image.NewNRGBA(image.Rect(0, 0, b.Dx(), b.Dy())),
+		Animations: map[string]*Animation{},
+	}
+	draw.Draw(s.Image, s.Image.Bounds(), img, b.Min, draw.Src)
+
+	for _, f := range d.Frames {
+		s.Frames = append(s.Frames, Frame{
+			Rect:     image.Rect(f.X, f.Y, f.X+f.W, f.Y+f.H),
+			Duration: time.Duration(f.Duration) * time.Millisecond,
+		})
+	}
+	if g := d.Grid; g != nil {
+		if g.W <= 0 || g.H <= 0 {
+			return nil, fmt.Errorf("invalid grid size %dx%d", g.W, g.H)
+		}
+		for y := 0; y+g.H <= b.Dy(); y += g.H {
+			for x := 0; x+g.W <= b.Dx(); x += g.W {
+				s.Frames = append(s.Frames, Frame{
+					Rect:     image.Rect(x, y, x+g.W, y+g.H),
+					Duration: time.Duration(g.Duration) * time.Millisecond,
+				})
+			}
+		}
+	}
+	if len(s.Frames) == 0 {
+		return nil, fmt.Errorf("no frames")
+	}
+	for i, f := range s.Frames {
+		if !f.Rect.In(s.Image.Bounds()) || f.Rect.Empty() {
+			return nil, fmt.Errorf("frame %d %v outside of the image", i, f.Rect)
+		}
+	}
+
+	for name, a := range d.Animations {
+		if len(a.Frames) == 0 {
+			return nil, fmt.Errorf("animation %q without frames", name)
+		}
+		for _, i := range a.Frames {
+			if i < 0 || i >= len(s.Frames) {
+				return nil, fmt.Errorf("animation %q: unknown frame %d", name, i)
+			}
+		}
+		s.Animations[name] = &Animation{Name: name, Frames: a.Frames, Loop: a.Loop}
+	}
+	if len(s.Animations) == 0 {
+		all := &Animation{Name: "default", Loop: true}
+		for i := range s.Frames {
+			all.Frames = append(all.Frames, i)
+		}
+		s.Animations[all.Name] = all
+	}
+	return s, nil
+}
+
+// Duration returns the duration of one run of the animation.
+func (s *Sheet) Duration(a *Animation) time.Duration {
+	var d time.Duration
+	for _, i := range a.Frames {
+		d += s.Frames[i].Duration
+	}
+	return d
+}
+
+// FrameAt returns the frame of the animation at the time el since its
+// start. Animations that do not loop stay at the last frame.
+func (s *Sheet) FrameAt(a *Animation, el time.Duration) int {
+	d := s.Duration(a)
+	if d <= 0 {
+		return a.Frames[0]
+	}
+	if a.Loop {
+		el %= d
+	}
+	for _, i := range a.Frames {
+		if el < s.Frames[i].Duration {
+			return i
+		}
+		el -= s.Frames[i].Duration
+	}
+	return a.Frames[len(a.Frames)-1]
+}
+
+// Flip mirrors a sprite.
+type Flip int
+
+const (
+	FlipH Flip = 1 << iota
+	FlipV
+)
+
+// Options change how a frame is drawn.
+type Options struct {
+	Flip Flip
+	// Swap replaces colors of the sheet, e.g. to draw the same sprite in
+	// the colors of different players. Keys are opaque colors.
+	Swap map[color.RGBA]color.RGBA
+	// Opacity scales the alpha of all pixels, 0 is treated as 1.
+	Opacity float64
+}
+
+// DrawFrame draws frame i with its top left corner at p. Transparent
+// pixels are skipped, semi-transparent pixels are blended.
+func (s *Sheet) DrawFrame(dst draw.Image, p image.Point, i int, opts *Options) {
+	if opts == nil {
+		opts = &Options{}
+	}
+	op := opts.Opacity
+	if op == 0 {
+		op = 1
+	}
+	r := s.Frames[i].Rect
+	w, h := r.Dx(), r.Dy()
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			sx, sy := x, y
+			if opts.Flip&FlipH != 0 {
+				sx = w - 1 - x
+			}
+			if opts.Flip&FlipV != 0 {
+				sy = h - 1 - y
+			}
+			c := s.Image.NRGBAAt(r.Min.X+sx, r.Min.Y+sy)
+			if c.A == 0 {
+				continue
+			}
+			if sc, ok := opts.Swap[color.RGBA{c.R, c.G, c.B, 255}]; ok {
+				c.R, c.G, c.B = sc.R, sc.G, sc.B
+			}
+			gfx.Blend(dst, p.X+x, p.Y+y, c, op)
+		}
+	}
+}
+
+// Sprite is an animated instance of a sheet.
+type Sprite struct {
+	Sheet *Sheet
+	Anim  *Animation
+	// Pos is the position of the top left corner.
+	Pos image.Point
+	Options
+}
+
+// New returns a sprite with the animation name of the sheet.
+func New(s *Sheet, anim string) (*Sprite, error) {
+	a, ok := s.Animations[anim]
+	if !ok {
+		return nil, fmt.Errorf("unknown animation %q", anim)
+	}
+	return &Sprite{Sheet: s, Anim: a}, nil
+}
+
+// Draw draws the frame of the animation at the time el.
+func (sp *Sprite) Draw(dst draw.Image, el time.Duration) {
+	sp.Sheet.DrawFrame(dst, sp.Pos, sp.Sheet.FrameAt(sp.Anim, el), &sp.Options)
+}
@@ -1,23 +1,33 @@
 package srv
 
 import (
+	"bufio"
 	"bytes"
+	"encoding/binary"
+	"errors"
 	"image"
 	"image/draw"
-	"image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
+	"io/ioutil"
 	"log"
 	"net"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/nfnt/resize"
-
 	"github.com/ktt-ol/go-insta"
+	"github.com/ktt-ol/go-insta/anim"
+	"github.com/ktt-ol/go-insta/clock"
+	"github.com/ktt-ol/go-insta/fit"
+	"github.com/ktt-ol/go-insta/resample"
 )
 
-func Server(ic insta.Client) {
+// Server accepts images, gifs and text commands on port 2323. Images are
+// fitted with fo, unless the request starts with a line like "FIT cover" or
+// "FIT contain 000000 nearest". Without a filter, the filter of fo is used.
+func Server(ic insta.Client, fo fit.Options) {
 	l, err := net.Listen("tcp", ":2323")
 	if err != nil {
 		log.Fatal(err)
@@ -36,60 +46,221 @@ func Server(ic insta.Client) {
 			defer c.Close()
 			log.Println("got conn")
 
-			// var maxBytes = 1024 * 1024 * 5
-			// r := io.LimitReader(c, int64(maxBytes))
-			var buf bytes.Buffer
-			tee := io.TeeReader(c, &buf)
-
-			config, format, err := image.DecodeConfig(tee)
-			log.Println("decode")
-			if err != nil {
+			br := bufio.NewReader(c)
+			if err := handle(ic, br, fo); err != nil {
 				c.Write([]byte(err.Error()))
 				log.Print(err)
-				return
 			}
+			// Shut down the connection.
+		}(conn)
+	}
+}
 
-			if config.Height*config.Width > 10000000 {
-				c.Write([]byte("too large"))
-				log.Print(err)
-				return
-			}
+// screenMu is held by requests while they draw. The countdown takes it
+// for every frame, so it pauses while other requests are shown.
+var screenMu sync.Mutex
+
+func handle(ic insta.Client, br *bufio.Reader, fo fit.Options) error {
+	for header, handle := range commands {
+		if head, _ := br.Peek(len(header)); string(head) == header {
+			return handle(ic, br)
+		}
+	}
+	if head, _ := br.Peek(len("FIT")); string(head) == "FIT" {
+		args, err := readArgs(br)
+		if err != nil {
+			return err
+		}
+		filter := fo.Filter
+		if fo, err = fit.Parse(args); err != nil {
+			return err
+		}
+		if fo.Filter == resample.Auto {
+			fo.Filter = filter
+		}
+	}
+	return showImage(ic, br, fo)
+}
+
+// maxUploadSize limits the bytes read for pngs, which are read as a whole
+// to find out whether they are animated.
+const maxUploadSize = 32 << 20
+
+func showImage(ic insta.Client, br *bufio.Reader, fo fit.Options) error {
+	var buf bytes.Buffer
+	tee := io.TeeReader(br, &buf)
+
+	config, format, err := image.DecodeConfig(tee)
+	log.Println("decode")
+	if err != nil {
+		return err
+	}
+
+	if config.Height*config.Width > 10000000 {
+		return errors.New("too large")
+	}
+
+	// decode as the data arrives, the client may keep the connection open
+	r := io.MultiReader(&buf, br)
+
+	var clip *anim.Clip
+	switch format {
+	case "gif":
+		clip, err = anim.DecodeGIF(r)
+	case "png":
+		var data []byte
+		if data, err = readPNG(io.LimitReader(r, maxUploadSize)); err != nil {
+			return err
+		}
+		if anim.IsAPNG(data) {
+			clip, err = anim.DecodeAPNG(bytes.NewReader(data))
+		} else {
+			r = bytes.NewReader(data)
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if clip != nil {
+		log.Println("decode all")
+		screenMu.Lock()
+		defer screenMu.Unlock()
+		insta.PlayClip(ic, clip.Fit(insta.ScreenWidth, insta.ScreenHeight, fo), 0)
+		return nil
+	}
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		return err
+	}
+
+	scr := insta.NewScreen()
+	draw.Draw(scr, scr.Bounds(), fit.Image(img, insta.ScreenWidth, insta.ScreenHeight, fo), image.ZP, draw.Src)
+
+	screenMu.Lock()
+	defer screenMu.Unlock()
+	ic.SetScreenImmediate(scr)
+	if timerRunning() {
+		// keep the image for a while, before the countdown continues
+		time.Sleep(imageTime)
+	}
+	return nil
+}
 
-			r := io.MultiReader(&buf, c)
-
-			if format == "gif" {
-				g, err := gif.DecodeAll(r)
-				log.Println("decode all")
-				if err != nil {
-					c.Write([]byte(err.Error()))
-					log.Print(err)
-					return
-				}
-				scr := insta.NewScreen()
-				bnds := image.Rect(0, 0, insta.ScreenWidth, insta.ScreenHeight)
-				for i := range g.Image {
-					img := resize.Resize(insta.ScreenWidth, insta.ScreenHeight, g.Image[i], resize.Bilinear)
-					draw.Draw(scr, bnds, img, image.ZP, draw.Over)
-					ic.SetScreenImmediate(scr)
-					time.Sleep(time.Duration(g.Delay[i]) * time.Millisecond)
-				}
-			} else {
-				img, _, err := image.Decode(r)
-				if err != nil {
-					c.Write([]byte(err.Error()))
-					log.Print(err)
-					return
-				}
-
-				img = resize.Resize(insta.ScreenWidth, insta.ScreenHeight, img, resize.Bilinear)
-
-				scr := insta.NewScreen()
-				bnds := image.Rect(0, 0, insta.ScreenWidth, insta.ScreenHeight)
-				draw.Draw(scr, bnds, img, image.ZP, draw.Over)
-
-				ic.SetScreenImmediate(scr)
+// readPNG reads a png up to its IEND chunk.
+func readPNG(r io.Reader) ([]byte, error) {
+	var data bytes.Buffer
+	if _, err := io.CopyN(&data, r, 8); err != nil {
+		return nil, err
+	}
+	head := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(r, head); err != nil {
+			return nil, err
+		}
+		data.Write(head)
+		// chunk data and crc
+		n := int64(binary.BigEndian.Uint32(head)) + 4
+		if _, err := io.CopyN(&data, r, n); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
 			}
-			// Shut down the connection.
-		}(conn)
+			return nil, err
+		}
+		if string(head[4:]) == "IEND" {
+			return data.Bytes(), nil
+		}
+	}
+}
+
+const maxTextSize = 4096
+
+// commands are text requests, selected by the first word of the request.
+var commands = map[string]func(insta.Client, *bufio.Reader) error{
+	"TEXT":  showText,
+	"TIMER": startTimer,
+}
+
+// readArgs returns the remainder of the first line after the command.
+func readArgs(r *bufio.Reader) (string, error) {
+	head, err := r.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if i := strings.IndexAny(head, " \t\n"); i >= 0 {
+		return strings.TrimSpace(head[i:]), nil
+	}
+	return "", nil
+}
+
+// showText plays a text message. The first line is "TEXT" followed by
+// optional effect names (e.g. "TEXT scroll,hue"), the remaining lines are
+// the message.
+func showText(ic insta.Client, r *bufio.Reader) error {
+	args, err := readArgs(r)
+	if err != nil {
+		return err
 	}
+	effects := insta.TextScroll
+	if args != "" {
+		effects, err = insta.ParseTextEffects(args)
+		if err != nil {
+			return err
+		}
+	}
+	msg, err := ioutil.ReadAll(io.LimitReader(r, maxTextSize))
+	if err != nil {
+		return err
+	}
+	screenMu.Lock()
+	defer screenMu.Unlock()
+	insta.PlayText(ic, insta.NewText(strings.TrimSpace(string(msg)), effects))
+	return nil
+}
+
+var (
+	timerMu sync.Mutex
+	timer   *clock.Countdown
+)
+
+// imageTime is how long still images are shown, when a countdown runs.
+const imageTime = 5 * time.Second
+
+func timerRunning() bool {
+	timerMu.Lock()
+	defer timerMu.Unlock()
+	return timer != nil
+}
+
+// startTimer starts a countdown in the background, replacing any running
+// countdown. The request is "TIMER 10m", "TIMER 18:30" or "TIMER stop".
+func startTimer(ic insta.Client, r *bufio.Reader) error {
+	args, err := readArgs(r)
+	if err != nil {
+		return err
+	}
+	timerMu.Lock()
+	defer timerMu.Unlock()
+	if timer != nil {
+		timer.Stop()
+		timer = nil
+	}
+	if args == "stop" {
+		return nil
+	}
+	end, err := clock.ParseTarget(args, time.Now())
+	if err != nil {
+		return err
+	}
+	timer = clock.NewCountdown(end)
+	timer.Lock = &screenMu
+	go func(t *clock.Countdown) {
+		t.Run(ic)
+		timerMu.Lock()
+		if timer == t {
+			timer = nil
+		}
+		timerMu.Unlock()
+	}(timer)
+	return nil
 }
@@ -1,69 +1,144 @@
 package insta
 
 import (
-	"image/color"
+	"image/draw"
 	"math"
 	"math/rand"
 	"time"
+
+	"github.com/ktt-ol/go-insta/theme"
+	"github.com/ktt-ol/go-insta/timing"
+	"github.com/ktt-ol/go-insta/tween"
+)
+
+const (
+	// starsPerSecond is the rate of new stars
+	starsPerSecond = 50
+	maxStars       = 400
+	// starLife is the time a star needs from the center to the border
+	starLife = 1200 * time.Millisecond
 )
 
 type star struct {
-	x     float64
-	y     float64
-	dx    float64
-	dy    float64
+	x, y  float64
+	born  time.Duration
 	alive bool
 	hue   int
+	fly   tween.Anim
 }
 
-func Spaceflight(c Client, duration time.Duration) {
-	stars := make([]star, 400)
+// launch starts the star from the center of a w x h field at the time
+// born.
+func (s *star) launch(born time.Duration, w, h int) {
+	*s = star{born: born, alive: true, hue: rand.Intn(360)}
+	dx := (rand.Float64()*2 + 0.2) - 1.1
+	dy := (rand.Float64()*2 + 0.2) - 1.1
+	// fly far enough to leave the field in any direction
+	k := float64(w) / math.Max(math.Hypot(dx, dy), 0.1)
+	cx, cy := float64(w/2), float64(h/2)
+	s.fly = tween.Parallel(
+		tween.Float(&s.x, cx, cx+dx*k, starLife, tween.InCubic),
+		tween.Float(&s.y, cy, cy+dy*k, starLife, tween.InCubic),
+	)
+}
+
+// Starfield is a flight through stars as Mode. Stars start from the center
+// of the target.
+type Starfield struct {
+	// Spawn adds new stars. The field is empty once all stars left the
+	// screen after Spawn was disabled.
+	Spawn bool
+
+	stars   []star
+	frame   *HDR
+	spawned int
+	last    time.Duration
+	alive   int
+}
+
+func NewStarfield() *Starfield {
+	return &Starfield{
+		Spawn: true,
+		stars: make([]star, maxStars),
+	}
+}
+
+// Alive returns the number of stars on the screen.
+func (f *Starfield) Alive() int {
+	return f.alive
+}
+
+func (f *Starfield) Draw(dst draw.Image, el time.Duration) {
+	w, h := dst.Bounds().Dx(), dst.Bounds().Dy()
+	if f.frame == nil || f.frame.W != w || f.frame.H != h {
+		// stars leave fading trails and add up to bright glows in the
+		// center, the frame is tone mapped once per screen
+		f.frame = NewHDR(w, h)
+		for i := range f.stars {
+			f.stars[i].alive = false
+		}
+	}
+	fw, fh := float64(w), float64(h)
+
+	// halve the trails every 40ms
+	f.frame.Scale(float32(math.Pow(0.5, (el-f.last).Seconds()*25)))
+	f.last = el
+
+	due := int(el.Seconds() * starsPerSecond)
+	pal := theme.Current().Cycled()
+	f.alive = 0
+	for i := range f.stars {
+		s := &f.stars[i]
+		if !s.alive && f.spawned < due && f.Spawn {
+			s.launch(el, w, h)
+			f.spawned++
+		}
+		if !s.alive {
+			continue
+		}
+		s.fly.Update(el - s.born)
+		if s.x < 0 || s.y < 0 || s.x >= fw || s.y >= fh || el-s.born >= starLife {
+			s.alive = false
+			continue
+		}
+		f.alive++
+
+		dist := math.Hypot((s.x-fw/2)/fw/2, (s.y-fh/2)/fh/2)
+		saturation := dist * 3
+		if saturation > 1.0 {
+			saturation = 1.0
+		}
+		brightness := float32(0.1 + dist*6)
 
-	till := time.Now().Add(duration)
+		col := shade(pal.Hue(float64(s.hue)), saturation, 1)
+		x, y := int(s.x), int(s.y)
+		f.frame.AddColor(x, y, col, brightness)
+		glow := brightness / 8
+		f.frame.AddColor(x-1, y, col, glow)
+		f.frame.AddColor(x+1, y, col, glow)
+		f.frame.AddColor(x, y-1, col, glow)
+		f.frame.AddColor(x, y+1, col, glow)
+	}
+	// skip stars that could not be added, if all slots are in use
+	if f.spawned < due {
+		f.spawned = due
+	}
+
+	f.frame.ToScreen(dst, 1.5, ToneReinhard)
+}
+
+// Spaceflight shows a starfield for the duration and waits till the last
+// star left the screen.
+func Spaceflight(c Client, duration time.Duration) {
+	f := NewStarfield()
+	start := timing.Now()
 	for {
+		el := timing.Since(start)
+		f.Spawn = el < duration
 		scr := NewScreen()
-		added := 0
-		alive := 0
-		for i, s := range stars {
-			if added < 2 && !s.alive && time.Now().Before(till) {
-				s = star{
-					x:     ScreenWidth / 2,
-					y:     ScreenHeight / 2,
-					dx:    (rand.Float64()*2 + 0.2) - 1.1,
-					dy:    (rand.Float64()*2 + 0.2) - 1.1,
-					alive: true,
-					hue:   rand.Intn(360),
-				}
-				added += 1
-			}
-			if !s.alive {
-				continue
-			}
-			alive += 1
-			s.x += s.dx
-			s.y += s.dy
-			s.dx *= 1.05
-			s.dy *= 1.05
-			if s.x < 0 || s.y < 0 || s.x >= ScreenWidth || s.y >= ScreenHeight {
-				s.alive = false
-			} else {
-				dist := math.Hypot((s.x-ScreenWidth/2)/ScreenWidth/2, (s.y-ScreenHeight/2)/ScreenHeight/2)
-				saturation := dist * 3
-				if saturation > 1.0 {
-					saturation = 1.0
-				}
-				brightness := 0.1 + dist*3
-				if brightness > 1.0 {
-					brightness = 1.0
-				}
-
-				r, g, b := HsvToRgb(float64(s.hue), saturation, brightness)
-				scr.Set(int(s.x), int(s.y), color.RGBA{uint8(r * 255), uint8(g * 255), uint8(b * 255), 128})
-			}
-			stars[i] = s
-		}
+		f.Draw(scr, el)
 		c.SetScreen(scr)
-		if alive == 0 {
+		if f.Alive() == 0 && !f.Spawn {
 			break
 		}
 	}
@@ -12,6 +12,8 @@ import (
 	"golang.org/x/image/font"
 	"golang.org/x/image/font/plan9font"
 	"golang.org/x/image/math/fixed"
+
+	"github.com/ktt-ol/go-insta/timing"
 )
 
 func ScrollText(c Client, text string, speed time.Duration, base int) {
@@ -43,7 +45,7 @@ func ScrollText(c Client, text string, speed time.Duration, base int) {
 		}
 		d.DrawString(text)
 		c.SetScreen(scr)
-		time.Sleep(speed)
+		timing.Sleep(speed)
 	}
-	time.Sleep(100 * time.Millisecond)
+	timing.Sleep(100 * time.Millisecond)
 }
@@ -0,0 +1,342 @@
+package insta
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+
+	"github.com/ktt-ol/go-insta/theme"
+)
+
+// TextEffect is a set of animations that are applied to a Text.
+type TextEffect int
+
+const (
+	// TextScroll moves each line from right to left across the screen.
+	TextScroll TextEffect = 1 << iota
+	// TextTypewriter reveals each line character by character.
+	TextTypewriter
+	// TextWave bounces each glyph on a sine wave.
+	TextWave
+	// TextHue cycles the hue of each glyph.
+	TextHue
+	// TextVScroll slides the next line in from below.
+	TextVScroll
+	// TextFade fades each line in and out.
+	TextFade
+	// TextBlink blinks all highlighted glyphs.
+	TextBlink
+)
+
+var textEffectNames = []struct {
+	name   string
+	effect TextEffect
+}{
+	{"scroll", TextScroll},
+	{"typewriter", TextTypewriter},
+	{"wave", TextWave},
+	{"hue", TextHue},
+	{"vscroll", TextVScroll},
+	{"fade", TextFade},
+	{"blink", TextBlink},
+}
+
+// ParseTextEffects parses a comma separated list of effect names
+// (e.g. "scroll,wave,hue").
+func ParseTextEffects(s string) (TextEffect, error) {
+	var e TextEffect
+	for _, name := range strings.Split(s, ",") {
+		name = strings.TrimSpace(strings.ToLower(name))
+		if name == "" {
+			continue
+		}
+		found := false
+		for _, n := range textEffectNames {
+			if n.name == name {
+				e |= n.effect
+				found = true
+				break
+			}
+		}
+		if !found {
+			return 0, fmt.Errorf("unknown text effect %q", name)
+		}
+	}
+	return e, nil
+}
+
+func (e TextEffect) String() string {
+	var names []string
+	for _, n := range textEffectNames {
+		if e&n.effect != 0 {
+			names = append(names, n.name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// Span marks the runes [Start, End) of a line.
+type Span struct {
+	Line, Start, End int
+}
+
+// Text is an animated text. Draw renders the text for any point in time,
+// so it can be used by modes that drive their own frame loop.
+type Text struct {
+	Lines   []string
+	Effects TextEffect
+
+	Face      font.Face
+	Color     color.RGBA
+	Highlight color.RGBA
+	// Highlights are drawn with the Highlight color and blink with TextBlink.
+	Highlights []Span
+
+	// ScrollSpeed in pixels per second for TextScroll.
+	ScrollSpeed float64
+	// TypeSpeed in characters per second for TextTypewriter.
+	TypeSpeed float64
+	// Hold is the time each line stays on the screen when it is not scrolled.
+	Hold time.Duration
+	// Transition is the duration of TextFade and TextVScroll.
+	Transition time.Duration
+	// WaveHeight is the amplitude of TextWave in pixels.
+	WaveHeight float64
+	// BlinkRate is the duration of a full on/off cycle of TextBlink.
+	BlinkRate time.Duration
+	// Width of the target for the duration of TextScroll.
+	Width int
+}
+
+// NewText returns a Text with default settings for the given message.
+// Lines are separated by newlines and words surrounded by *stars* are
+// highlighted.
+func NewText(msg string, effects TextEffect) *Text {
+	th := theme.Current()
+	t := &Text{
+		Effects:     effects,
+		Face:        basicfont.Face7x13,
+		Color:       th.Foreground,
+		Highlight:   th.Accent,
+		ScrollSpeed: 25,
+		TypeSpeed:   8,
+		Hold:        2 * time.Second,
+		Transition:  500 * time.Millisecond,
+		WaveHeight:  3,
+		BlinkRate:   time.Second,
+		Width:       ScreenWidth,
+	}
+	for i, l := range strings.Split(msg, "\n") {
+		line, spans := parseHighlights(l)
+		for _, s := range spans {
+			s.Line = i
+			t.Highlights = append(t.Highlights, s)
+		}
+		t.Lines = append(t.Lines, line)
+	}
+	return t
+}
+
+func parseHighlights(line string) (string, []Span) {
+	var (
+		b     strings.Builder
+		spans []Span
+		n     int
+		start = -1
+	)
+	for _, r := range line {
+		if r == '*' {
+			if start < 0 {
+				start = n
+			} else {
+				spans = append(spans, Span{Start: start, End: n})
+				start = -1
+			}
+			continue
+		}
+		b.WriteRune(r)
+		n++
+	}
+	return b.String(), spans
+}
+
+func (t *Text) lineWidth(i int) int {
+	return font.MeasureString(t.Face, t.Lines[i]).Ceil()
+}
+
+// lineDuration returns how long line i is shown, without the transition
+// to the next line.
+func (t *Text) lineDuration(i int) time.Duration {
+	d := t.Hold
+	if t.Effects&TextScroll != 0 && t.ScrollSpeed > 0 {
+		px := float64(t.lineWidth(i) + t.Width)
+		d = time.Duration(px / t.ScrollSpeed * float64(time.Second))
+	} else if t.Effects&TextTypewriter != 0 && t.TypeSpeed > 0 {
+		n := float64(utf8.RuneCountInString(t.Lines[i]))
+		d += time.Duration(n / t.TypeSpeed * float64(time.Second))
+	}
+	if t.Effects&TextFade != 0 {
+		d += 2 * t.Transition
+	}
+	return d
+}
+
+func (t *Text) transition() time.Duration {
+	if t.Effects&TextVScroll != 0 {
+		return t.Transition
+	}
+	return 0
+}
+
+// Duration returns the total duration of the animation.
+func (t *Text) Duration() time.Duration {
+	var d time.Duration
+	for i := range t.Lines {
+		d += t.lineDuration(i)
+		if i < len(t.Lines)-1 {
+			d += t.transition()
+		}
+	}
+	return d
+}
+
+// Draw draws the text at time el since the start of the animation onto s.
+func (t *Text) Draw(s draw.Image, el time.Duration) {
+	for i := range t.Lines {
+		ld := t.lineDuration(i)
+		if el < ld {
+			t.drawLine(s, i, el, 0)
+			return
+		}
+		el -= ld
+		if i == len(t.Lines)-1 {
+			return
+		}
+		tr := t.transition()
+		if el < tr {
+			// slide current line out to the top and the next line in,
+			// both at their last and first fully visible time
+			var fade time.Duration
+			if t.Effects&TextFade != 0 {
+				fade = t.Transition
+			}
+			h := s.Bounds().Dy()
+			off := int(float64(h) * float64(el) / float64(tr))
+			t.drawLine(s, i, ld-fade, -off)
+			t.drawLine(s, i+1, fade, h-off)
+			return
+		}
+		el -= tr
+	}
+}
+
+func (t *Text) drawLine(s draw.Image, i int, el time.Duration, dy int) {
+	line := t.Lines[i]
+	ld := t.lineDuration(i)
+	sec := el.Seconds()
+
+	alpha := 1.0
+	if t.Effects&TextFade != 0 && t.Transition > 0 {
+		if el < t.Transition {
+			alpha = float64(el) / float64(t.Transition)
+		} else if el > ld-t.Transition {
+			alpha = float64(ld-el) / float64(t.Transition)
+		}
+		if alpha < 0 {
+			alpha = 0
+		}
+	}
+
+	visible := utf8.RuneCountInString(line)
+	if t.Effects&TextTypewriter != 0 && t.Effects&TextScroll == 0 {
+		typeEl := el
+		if t.Effects&TextFade != 0 {
+			typeEl -= t.Transition
+		}
+		if n := int(typeEl.Seconds() * t.TypeSpeed); n < visible {
+			visible = n
+		}
+	}
+
+	w, h := s.Bounds().Dx(), s.Bounds().Dy()
+	m := t.Face.Metrics()
+	base := (h+m.Ascent.Ceil()-m.Descent.Ceil())/2 + dy
+	x := fixed.I((w - t.lineWidth(i)) / 2)
+	if t.Effects&TextScroll != 0 {
+		x = fixed.I(w - int(sec*t.ScrollSpeed))
+	}
+
+	d := &font.Drawer{Dst: s, Face: t.Face}
+	prev := rune(-1)
+	for n, r := range []rune(line) {
+		if n >= visible {
+			break
+		}
+		if prev >= 0 {
+			x += t.Face.Kern(prev, r)
+		}
+		prev = r
+		adv, _ := t.Face.GlyphAdvance(r)
+
+		if px := x.Floor(); px+adv.Ceil() >= 0 && px < w {
+			y := base
+			if t.Effects&TextWave != 0 {
+				y += int(math.Round(t.WaveHeight * math.Sin(sec*6+float64(n)*0.7)))
+			}
+			c, ok := t.glyphColor(i, n, sec)
+			if ok {
+				d.Src = image.NewUniform(scaleAlpha(c, alpha))
+				d.Dot = fixed.Point26_6{X: x, Y: fixed.I(y)}
+				d.DrawString(string(r))
+			}
+		}
+		x += adv
+	}
+}
+
+// glyphColor returns the color of rune n of line i. It returns false if the
+// glyph is hidden by TextBlink.
+func (t *Text) glyphColor(i, n int, sec float64) (color.RGBA, bool) {
+	for _, h := range t.Highlights {
+		if h.Line == i && n >= h.Start && n < h.End {
+			if t.Effects&TextBlink != 0 && t.BlinkRate > 0 {
+				phase := math.Mod(sec, t.BlinkRate.Seconds()) / t.BlinkRate.Seconds()
+				if phase >= 0.5 {
+					return color.RGBA{}, false
+				}
+			}
+			return t.Highlight, true
+		}
+	}
+	if t.Effects&TextHue != 0 {
+		return HsvToColor(math.Mod(sec*90+float64(n)*25, 360), 0.8, 1), true
+	}
+	return t.Color, true
+}
+
+func scaleAlpha(c color.RGBA, a float64) color.RGBA {
+	if a >= 1 {
+		return c
+	}
+	return color.RGBA{
+		uint8(float64(c.R) * a),
+		uint8(float64(c.G) * a),
+		uint8(float64(c.B) * a),
+		uint8(float64(c.A) * a),
+	}
+}
+
+// PlayText plays the text animation till its end.
+func PlayText(c Client, t *Text) {
+	RunMode(c, t, t.Duration())
+	c.SetScreen(NewScreen())
+}
@@ -0,0 +1,116 @@
+// Package theme provides named color palettes and a global theme that
+// modes use for their colors.
+package theme
+
+import (
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/ktt-ol/go-insta/colors"
+)
+
+// Palette is a cyclic list of colors. It is used either as indexed set with
+// Index or as smooth gradient with At, that runs through all colors and
+// back to the first.
+type Palette struct {
+	Name   string
+	Colors []color.RGBA
+	// Offset shifts all positions of At and Index, see Shift.
+	Offset float64
+}
+
+// Index returns the i-th color. Indices wrap around.
+func (p *Palette) Index(i int) color.RGBA {
+	n := len(p.Colors)
+	if n == 0 {
+		return color.RGBA{}
+	}
+	i += int(math.Floor(p.Offset * float64(n)))
+	return p.Colors[(i%n+n)%n]
+}
+
+// At returns the color at position t, interpolated in linear light. The
+// colors are at the positions 0, 1/n, 2/n, ... and positions wrap around at
+// 1.
+func (p *Palette) At(t float64) color.RGBA {
+	n := len(p.Colors)
+	if n == 0 {
+		return color.RGBA{}
+	}
+	t = math.Mod(t+p.Offset, 1)
+	if t < 0 {
+		t++
+	}
+	pos := t * float64(n)
+	i := int(pos)
+	return colors.Lerp(p.Colors[i%n], p.Colors[(i+1)%n], pos-float64(i))
+}
+
+// Hue returns At(h/360), to replace HSV colors with a fixed saturation
+// and value.
+func (p *Palette) Hue(h float64) color.RGBA {
+	return p.At(h / 360)
+}
+
+// Shift returns a copy of the palette with all positions shifted by t.
+func (p *Palette) Shift(t float64) *Palette {
+	q := *p
+	q.Offset = math.Mod(p.Offset+t, 1)
+	return &q
+}
+
+// Cycle returns the palette shifted by el/period, the colors rotate once
+// per period.
+func (p *Palette) Cycle(el, period time.Duration) *Palette {
+	if period <= 0 {
+		return p
+	}
+	return p.Shift(float64(el%period) / float64(period))
+}
+
+// Gradient returns a palette with n colors that runs through the stops
+// with equal distances, e.g. to turn a few key colors into a smooth
+// indexed set.
+func Gradient(name string, n int, stops ...color.RGBA) *Palette {
+	p := &Palette{Name: name, Colors: stops}
+	q := &Palette{Name: name, Colors: make([]color.RGBA, n)}
+	for i := range q.Colors {
+		q.Colors[i] = p.At(float64(i) / float64(n))
+	}
+	return q
+}
+
+// hues returns a palette with n HSV hues of the same saturation and value.
+func hues(name string, n int, s, v float64) *Palette {
+	p := &Palette{Name: name, Colors: make([]color.RGBA, n)}
+	for i := range p.Colors {
+		p.Colors[i] = colors.HSV(float64(i)*360/float64(n), s, v)
+	}
+	return p
+}
+
+func rgb(c uint32) color.RGBA {
+	return color.RGBA{uint8(c >> 16), uint8(c >> 8), uint8(c), 255}
+}
+
+var palettes = map[string]*Palette{}
+
+func addPalette(p *Palette) *Palette {
+	palettes[p.Name] = p
+	return p
+}
+
+var (
+	Rainbow   = addPalette(hues("rainbow", 36, 0.7, 0.8))
+	Space     = addPalette(&Palette{Name: "space", Colors: []color.RGBA{rgb(0x0b0d3a), rgb(0x3b1c8c), rgb(0x8a2be2), rgb(0x00b4d8), rgb(0xe0f7ff), rgb(0x2a4bd7)}})
+	Christmas = addPalette(&Palette{Name: "christmas", Colors: []color.RGBA{rgb(0xd00000), rgb(0xffffff), rgb(0x008000), rgb(0xffc000)}})
+	Amber     = addPalette(&Palette{Name: "amber", Colors: []color.RGBA{rgb(0x301000), rgb(0xff8000), rgb(0xffb000), rgb(0x804000)}})
+	Fire      = addPalette(&Palette{Name: "fire", Colors: []color.RGBA{rgb(0x200000), rgb(0xc00000), rgb(0xff6000), rgb(0xffe000), rgb(0xff6000), rgb(0xc00000)}})
+	Ocean     = addPalette(&Palette{Name: "ocean", Colors: []color.RGBA{rgb(0x001030), rgb(0x0050a0), rgb(0x00a0c0), rgb(0x60e0e0), rgb(0x0050a0)}})
+)
+
+// PaletteByName returns the palette with the name or nil.
+func PaletteByName(name string) *Palette {
+	return palettes[name]
+}
@@ -0,0 +1,162 @@
+package theme
+
+import (
+	"fmt"
+	"image/color"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ktt-ol/go-insta/timing"
+)
+
+// Theme is a set of colors used by all modes.
+type Theme struct {
+	Name string
+	// Palette is used for cells, graphs and other colorful content.
+	Palette *Palette
+	// Foreground is used for text and digits.
+	Foreground color.RGBA
+	// Accent is used for highlights.
+	Accent color.RGBA
+	// Dim is used for inactive parts, e.g. the unlit bits of the clock.
+	Dim color.RGBA
+	// Players are the colors of the players in games.
+	Players []color.RGBA
+	// CycleEvery rotates the palette once in this interval, see Color.
+	CycleEvery time.Duration
+}
+
+// Color returns the palette color at position v, cycled with the time if
+// CycleEvery is set. Modes that color many pixels use Cycled once per frame
+// instead.
+func (t *Theme) Color(v float64) color.RGBA {
+	return t.Palette.At(v + t.phase())
+}
+
+// Cycled returns the palette as it is shifted by CycleEvery at the current
+// time.
+func (t *Theme) Cycled() *Palette {
+	if t.CycleEvery <= 0 {
+		return t.Palette
+	}
+	return t.Palette.Shift(t.phase())
+}
+
+func (t *Theme) phase() float64 {
+	if t.CycleEvery <= 0 {
+		return 0
+	}
+	el := time.Duration(timing.Now().UnixNano())
+	return float64(el%t.CycleEvery) / float64(t.CycleEvery)
+}
+
+// Hue returns Color(h/360).
+func (t *Theme) Hue(h float64) color.RGBA {
+	return t.Color(h / 360)
+}
+
+// Player returns the color of player i.
+func (t *Theme) Player(i int) color.RGBA {
+	if len(t.Players) == 0 {
+		return t.Foreground
+	}
+	return t.Players[i%len(t.Players)]
+}
+
+var themes = map[string]*Theme{
+	"default": {
+		Name:       "default",
+		Palette:    Rainbow,
+		Foreground: rgb(0xffffff),
+		Accent:     rgb(0xff2800),
+		Dim:        rgb(0x1e1e28),
+		Players:    []color.RGBA{rgb(0xffff00), rgb(0xff00ff)},
+	},
+	"space": {
+		Name:       "space",
+		Palette:    Space,
+		Foreground: rgb(0xe0f7ff),
+		Accent:     rgb(0x00b4d8),
+		Dim:        rgb(0x10103a),
+		Players:    []color.RGBA{rgb(0x00e0ff), rgb(0xb060ff)},
+	},
+	"christmas": {
+		Name:       "christmas",
+		Palette:    Christmas,
+		Foreground: rgb(0xffffff),
+		Accent:     rgb(0xd00000),
+		Dim:        rgb(0x002000),
+		Players:    []color.RGBA{rgb(0xd00000), rgb(0x00c000)},
+		CycleEvery: 10 * time.Second,
+	},
+	"amber": {
+		Name:       "amber",
+		Palette:    Amber,
+		Foreground: rgb(0xffb000),
+		Accent:     rgb(0xff8000),
+		Dim:        rgb(0x301000),
+		Players:    []color.RGBA{rgb(0xffb000), rgb(0xa05000)},
+	},
+	"fire": {
+		Name:       "fire",
+		Palette:    Fire,
+		Foreground: rgb(0xffe000),
+		Accent:     rgb(0xff3000),
+		Dim:        rgb(0x300800),
+		Players:    []color.RGBA{rgb(0xffe000), rgb(0xff3000)},
+		CycleEvery: 5 * time.Second,
+	},
+	"ocean": {
+		Name:       "ocean",
+		Palette:    Ocean,
+		Foreground: rgb(0xe0ffff),
+		Accent:     rgb(0x00e0c0),
+		Dim:        rgb(0x001830),
+		Players:    []color.RGBA{rgb(0x60e0e0), rgb(0xffffff)},
+	},
+}
+
+var (
+	mu      sync.Mutex
+	current = themes["default"]
+)
+
+// Current returns the active theme.
+func Current() *Theme {
+	mu.Lock()
+	defer mu.Unlock()
+	return current
+}
+
+// Set activates the theme with the name.
+func Set(name string) error {
+	t, ok := themes[name]
+	if !ok {
+		return fmt.Errorf("unknown theme %q, expected one of %s", name, strings.Join(Names(), ", "))
+	}
+	mu.Lock()
+	current = t
+	mu.Unlock()
+	return nil
+}
+
+// Register adds or replaces a theme.
+func Register(t *Theme) {
+	mu.Lock()
+	themes[t.Name] = t
+	mu.Unlock()
+}
+
+// Names returns the names of all themes.
+func Names() []string {
+	mu.Lock()
+	defer mu.Unlock()
+	var names []string
+	for n := range themes {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,86 @@
+// Package timing is the time source of all modes. It is the wall clock,
+// unless it is replaced by a virtual clock, e.g. to render modes offline
+// faster than real time and with reproducible results.
+package timing
+
+import (
+	"sync"
+	"time"
+)
+
+// Source returns the current time and waits for durations.
+type Source interface {
+	Now() time.Time
+	Sleep(d time.Duration)
+}
+
+type wall struct{}
+
+func (wall) Now() time.Time        { return time.Now() }
+func (wall) Sleep(d time.Duration) { time.Sleep(d) }
+
+// Wall is the real time.
+var Wall Source = wall{}
+
+var (
+	mu  sync.Mutex
+	src = Wall
+)
+
+// Set replaces the time source of all modes.
+func Set(s Source) {
+	mu.Lock()
+	src = s
+	mu.Unlock()
+}
+
+func source() Source {
+	mu.Lock()
+	defer mu.Unlock()
+	return src
+}
+
+// Now returns the current time of the time source.
+func Now() time.Time {
+	return source().Now()
+}
+
+// Since returns the time passed since t.
+func Since(t time.Time) time.Duration {
+	return Now().Sub(t)
+}
+
+// Sleep waits for d, a virtual clock advances by d instead.
+func Sleep(d time.Duration) {
+	if d > 0 {
+		source().Sleep(d)
+	}
+}
+
+// Virtual is a clock that only moves with Sleep and Advance.
+type Virtual struct {
+	mu sync.Mutex
+	t  time.Time
+}
+
+// NewVirtual returns a virtual clock starting at t.
+func NewVirtual(t time.Time) *Virtual {
+	return &Virtual{t: t}
+}
+
+func (v *Virtual) Now() time.Time {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.t
+}
+
+func (v *Virtual) Sleep(d time.Duration) {
+	v.Advance(d)
+}
+
+// Advance moves the clock forward by d.
+func (v *Virtual) Advance(d time.Duration) {
+	v.mu.Lock()
+	v.t = v.t.Add(d)
+	v.mu.Unlock()
+}
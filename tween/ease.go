@@ -0,0 +1,128 @@
+// Package tween animates values over time with easing curves. Animations
+// are driven by the elapsed time, not by frames, so they run at the same
+// speed with any frame rate.
+package tween
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// Ease maps the progress t (0-1) of an animation to the progress of the
+// value. Most curves return 0 for 0 and 1 for 1, some overshoot in between.
+type Ease func(t float64) float64
+
+func Linear(t float64) float64 { return t }
+
+func InQuad(t float64) float64    { return t * t }
+func OutQuad(t float64) float64   { return 1 - InQuad(1-t) }
+func InOutQuad(t float64) float64 { return inOut(InQuad, t) }
+
+func InCubic(t float64) float64    { return t * t * t }
+func OutCubic(t float64) float64   { return 1 - InCubic(1-t) }
+func InOutCubic(t float64) float64 { return inOut(InCubic, t) }
+
+func InSine(t float64) float64    { return 1 - math.Cos(t*math.Pi/2) }
+func OutSine(t float64) float64   { return math.Sin(t * math.Pi / 2) }
+func InOutSine(t float64) float64 { return (1 - math.Cos(t*math.Pi)) / 2 }
+
+func InExpo(t float64) float64 {
+	if t <= 0 {
+		return 0
+	}
+	return math.Pow(2, 10*t-10)
+}
+func OutExpo(t float64) float64   { return 1 - InExpo(1-t) }
+func InOutExpo(t float64) float64 { return inOut(InExpo, t) }
+
+// InBack pulls back a bit before it starts.
+func InBack(t float64) float64 {
+	const s = 1.70158
+	return t * t * ((s+1)*t - s)
+}
+func OutBack(t float64) float64   { return 1 - InBack(1-t) }
+func InOutBack(t float64) float64 { return inOut(InBack, t) }
+
+// OutElastic overshoots and swings into the target.
+func OutElastic(t float64) float64 {
+	if t <= 0 || t >= 1 {
+		return t
+	}
+	return math.Pow(2, -10*t)*math.Sin((t*10-0.75)*2*math.Pi/3) + 1
+}
+func InElastic(t float64) float64 { return 1 - OutElastic(1-t) }
+
+// OutBounce bounces on the target like a dropped ball.
+func OutBounce(t float64) float64 {
+	const n, d = 7.5625, 2.75
+	switch {
+	case t < 1/d:
+		return n * t * t
+	case t < 2/d:
+		t -= 1.5 / d
+		return n*t*t + 0.75
+	case t < 2.5/d:
+		t -= 2.25 / d
+		return n*t*t + 0.9375
+	default:
+		t -= 2.625 / d
+		return n*t*t + 0.984375
+	}
+}
+func InBounce(t float64) float64 { return 1 - OutBounce(1-t) }
+
+// Step jumps from 0 to 1 at the end.
+func Step(t float64) float64 {
+	if t >= 1 {
+		return 1
+	}
+	return 0
+}
+
+// inOut runs in for the first half and mirrored for the second half.
+func inOut(in Ease, t float64) float64 {
+	if t < 0.5 {
+		return in(2*t) / 2
+	}
+	return 1 - in(2-2*t)/2
+}
+
+var eases = map[string]Ease{
+	"linear":     Linear,
+	"inquad":     InQuad,
+	"outquad":    OutQuad,
+	"inoutquad":  InOutQuad,
+	"incubic":    InCubic,
+	"outcubic":   OutCubic,
+	"inoutcubic": InOutCubic,
+	"insine":     InSine,
+	"outsine":    OutSine,
+	"inoutsine":  InOutSine,
+	"inexpo":     InExpo,
+	"outexpo":    OutExpo,
+	"inoutexpo":  InOutExpo,
+	"inback":     InBack,
+	"outback":    OutBack,
+	"inoutback":  InOutBack,
+	"inelastic":  InElastic,
+	"outelastic": OutElastic,
+	"inbounce":   InBounce,
+	"outbounce":  OutBounce,
+	"step":       Step,
+}
+
+// ParseEase returns the curve with the name of the function, e.g.
+// "inOutQuad". Names are case insensitive.
+func ParseEase(name string) (Ease, error) {
+	if e, ok := eases[strings.ToLower(name)]; ok {
+		return e, nil
+	}
+	var names []string
+	for n := range eases {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return nil, fmt.Errorf("unknown easing %q, expected one of %s", name, strings.Join(names, ", "))
+}
@@ -0,0 +1,220 @@
+package tween
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"time"
+
+	"github.com/ktt-ol/go-insta/colors"
+)
+
+// Forever is the duration of endless animations.
+const Forever = time.Duration(math.MaxInt64)
+
+// Anim is an animation. Update sets the animated values for the time el
+// since the start of the animation. el can jump forwards and backwards, so
+// skipped frames do not change the result.
+type Anim interface {
+	Duration() time.Duration
+	Update(el time.Duration)
+}
+
+// Tween calls Set with the progress of the eased curve.
+type Tween struct {
+	D    time.Duration
+	Ease Ease
+	// Set is called with the eased progress, 0 at the start and 1 at the
+	// end.
+	Set func(t float64)
+}
+
+// Func returns a tween that calls fn with the eased progress.
+func Func(d time.Duration, ease Ease, fn func(t float64)) *Tween {
+	if ease == nil {
+		ease = Linear
+	}
+	return &Tween{D: d, Ease: ease, Set: fn}
+}
+
+// Float animates *v from a to b.
+func Float(v *float64, a, b float64, d time.Duration, ease Ease) *Tween {
+	return Func(d, ease, func(t float64) { *v = a + (b-a)*t })
+}
+
+// Point animates *p from a to b, e.g. the position of a sprite.
+func Point(p *image.Point, a, b image.Point, d time.Duration, ease Ease) *Tween {
+	return Func(d, ease, func(t float64) {
+		p.X = a.X + int(math.Round(float64(b.X-a.X)*t))
+		p.Y = a.Y + int(math.Round(float64(b.Y-a.Y)*t))
+	})
+}
+
+// Color animates *c from a to b in linear light.
+func Color(c *color.RGBA, a, b color.RGBA, d time.Duration, ease Ease) *Tween {
+	return Func(d, ease, func(t float64) { *c = colors.Lerp(a, b, t) })
+}
+
+func (tw *Tween) Duration() time.Duration {
+	return tw.D
+}
+
+func (tw *Tween) Update(el time.Duration) {
+	t := 1.0
+	if tw.D > 0 {
+		t = float64(el) / float64(tw.D)
+	}
+	if t < 0 {
+		t = 0
+	} else if t > 1 {
+		t = 1
+	}
+	tw.Set(tw.Ease(t))
+}
+
+// Delay is an animation that does nothing for d, e.g. to add pauses to a
+// sequence.
+func Delay(d time.Duration) Anim {
+	return Func(d, Linear, func(float64) {})
+}
+
+type entry struct {
+	start time.Duration
+	anim  Anim
+}
+
+// Timeline runs animations at fixed start times. Animations that started
+// are updated in the order of their start time, so later animations of the
+// same value take over.
+type Timeline struct {
+	entries []entry
+	last    time.Duration
+}
+
+// Add adds a that starts at the time at.
+func (tl *Timeline) Add(at time.Duration, a Anim) *Timeline {
+	i := len(tl.entries)
+	for i > 0 && tl.entries[i-1].start > at {
+		i--
+	}
+	tl.entries = append(tl.entries, entry{})
+	copy(tl.entries[i+1:], tl.entries[i:])
+	tl.entries[i] = entry{start: at, anim: a}
+	return tl
+}
+
+// Then adds a after the end of the timeline.
+func (tl *Timeline) Then(a Anim) *Timeline {
+	return tl.Add(tl.Duration(), a)
+}
+
+func (tl *Timeline) Duration() time.Duration {
+	var d time.Duration
+	for _, e := range tl.entries {
+		ed := e.anim.Duration()
+		if ed == Forever {
+			return Forever
+		}
+		if e.start+ed > d {
+			d = e.start + ed
+		}
+	}
+	return d
+}
+
+func (tl *Timeline) Update(el time.Duration) {
+	if el < tl.last {
+		// reset the entries that didn't start yet, the latest first, so
+		// that earlier entries win for shared values
+		for i := len(tl.entries) - 1; i >= 0; i-- {
+			if e := tl.entries[i]; e.start > el && e.start <= tl.last {
+				e.anim.Update(0)
+			}
+		}
+	}
+	tl.last = el
+	for _, e := range tl.entries {
+		if e.start > el {
+			break
+		}
+		e.anim.Update(el - e.start)
+	}
+}
+
+// Sequence runs the animations one after another.
+func Sequence(anims ...Anim) *Timeline {
+	tl := &Timeline{}
+	for _, a := range anims {
+		tl.Then(a)
+	}
+	return tl
+}
+
+// Parallel runs all animations at the same time.
+func Parallel(anims ...Anim) *Timeline {
+	tl := &Timeline{}
+	for _, a := range anims {
+		tl.Add(0, a)
+	}
+	return tl
+}
+
+type repeat struct {
+	a    Anim
+	n    int
+	yoyo bool
+}
+
+// Repeat runs a n times, or forever if n is 0.
+func Repeat(a Anim, n int) Anim {
+	return &repeat{a: a, n: n}
+}
+
+// Yoyo runs a forwards and backwards n times, or forever if n is 0.
+func Yoyo(a Anim, n int) Anim {
+	return &repeat{a: a, n: n, yoyo: true}
+}
+
+func (r *repeat) cycle() time.Duration {
+	d := r.a.Duration()
+	if r.yoyo {
+		d *= 2
+	}
+	return d
+}
+
+func (r *repeat) Duration() time.Duration {
+	if r.n <= 0 || r.a.Duration() == Forever {
+		return Forever
+	}
+	return r.cycle() * time.Duration(r.n)
+}
+
+func (r *repeat) Update(el time.Duration) {
+	d, c := r.a.Duration(), r.cycle()
+	if d == Forever {
+		r.a.Update(el)
+		return
+	}
+	if c <= 0 {
+		r.a.Update(0)
+		return
+	}
+	if end := r.Duration(); el >= end {
+		// keep the state at the end of the last cycle
+		if r.yoyo {
+			r.a.Update(0)
+		} else {
+			r.a.Update(d)
+		}
+		return
+	}
+	if el < 0 {
+		el = 0
+	}
+	el %= c
+	if el > d {
+		el = c - el
+	}
+	r.a.Update(el)
+}
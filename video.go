@@ -0,0 +1,74 @@
+package insta
+
+import (
+	"image"
+	"image/draw"
+	"io"
+	"log"
+	"time"
+
+	"github.com/ktt-ol/go-insta/fit"
+	"github.com/ktt-ol/go-insta/resample"
+	"github.com/ktt-ol/go-insta/timing"
+	"github.com/ktt-ol/go-insta/video"
+)
+
+// PlayVideo plays the video file fname, or stdin for "-", see video.Open.
+// Files loop till d passed, or play once if d is 0. Frames are shown at
+// the frame rate of the video, frames that the client could not show in
+// time are dropped.
+func PlayVideo(c Client, fname string, raw video.Raw, d time.Duration, fo fit.Options) {
+	start := timing.Now()
+	for {
+		s, closer, err := video.Open(fname, raw)
+		if err != nil {
+			log.Print(err)
+			return
+		}
+		n := playStream(c, s, start, d, fo)
+		closer.Close()
+		if fname == "-" || d == 0 || n == 0 || timing.Since(start) >= d {
+			return
+		}
+	}
+}
+
+// playStream plays s till its end or till d since start passed and
+// returns the number of frames read.
+func playStream(c Client, s video.Stream, start time.Time, d time.Duration, fo fit.Options) int {
+	fps := s.FrameRate()
+	if fps <= 0 {
+		fps = 25
+	}
+	frameDur := time.Duration(float64(time.Second) / fps)
+	begin := timing.Now()
+	for n := 0; ; n++ {
+		if d > 0 && timing.Since(start) >= d {
+			return n
+		}
+		frame, err := s.Next()
+		if err != nil {
+			if err != io.EOF {
+				log.Print(err)
+			}
+			return n
+		}
+		if fo.Filter == resample.Auto {
+			// use the same filter for all frames
+			fo.Filter = resample.Choose(frame, ScreenWidth, ScreenHeight)
+		}
+
+		due := time.Duration(n) * frameDur
+		el := timing.Since(begin)
+		if el > due+frameDur {
+			// the next frame is already due, skip scaling and showing
+			continue
+		}
+		if el < due {
+			timing.Sleep(due - el)
+		}
+		scr := NewScreen()
+		draw.Draw(scr, scr.Bounds(), fit.Image(frame, ScreenWidth, ScreenHeight, fo), image.ZP, draw.Src)
+		c.SetScreen(scr)
+	}
+}
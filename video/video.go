@@ -0,0 +1,118 @@
+// Package video decodes uncompressed video streams: YUV4MPEG2 (.y4m) files
+// and raw RGB frame dumps. Both are written by ffmpeg without any codec,
+// e.g. "ffmpeg -i clip.mp4 -vf scale=108:72 -f yuv4mpegpipe clip.y4m".
+package video
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Stream is a sequence of video frames.
+type Stream interface {
+	// Next returns the next frame, or io.EOF at the end of the stream. The
+	// returned image is reused by the following call.
+	Next() (*image.RGBA, error)
+	// FrameRate returns the frames per second.
+	FrameRate() float64
+	// Size returns the size of the frames.
+	Size() (w, h int)
+}
+
+// Raw describes raw RGB streams without header, three bytes per pixel,
+// row by row.
+type Raw struct {
+	W, H int
+	FPS  float64
+}
+
+// ParseRaw parses the size and frame rate of raw streams like "54x36@25".
+// The frame rate is optional and defaults to 25.
+func ParseRaw(s string) (Raw, error) {
+	r := Raw{FPS: 25}
+	size := s
+	if i := strings.IndexByte(s, '@'); i >= 0 {
+		size = s[:i]
+		fps, err := strconv.ParseFloat(s[i+1:], 64)
+		if err != nil || fps <= 0 {
+			return r, fmt.Errorf("invalid frame rate in %q", s)
+		}
+		r.FPS = fps
+	}
+	if _, err := fmt.Sscanf(size, "%dx%d", &r.W, &r.H); err != nil || r.W <= 0 || r.H <= 0 {
+		return r, fmt.Errorf("invalid raw video size %q, expected WxH@FPS", s)
+	}
+	return r, nil
+}
+
+const y4mMagic = "YUV4MPEG2 "
+
+// Open opens the video file fname, or stdin for "-". YUV4MPEG2 streams are
+// detected by their header, all other streams are read as raw RGB with the
+// size of raw.
+func Open(fname string, raw Raw) (Stream, io.Closer, error) {
+	var f *os.File
+	if fname == "-" {
+		f = os.Stdin
+	} else {
+		var err error
+		if f, err = os.Open(fname); err != nil {
+			return nil, nil, err
+		}
+	}
+	br := bufio.NewReaderSize(f, 1<<16)
+	var s Stream
+	var err error
+	if head, _ := br.Peek(len(y4mMagic)); bytes.Equal(head, []byte(y4mMagic)) {
+		s, err = NewY4M(br)
+	} else if raw.W > 0 && raw.H > 0 {
+		s = NewRaw(br, raw)
+	} else {
+		err = errors.New("not a YUV4MPEG2 stream and no raw video size given")
+	}
+	if err != nil {
+		f.Close()
+		return nil, nil, fmt.Errorf("%s: %v", fname, err)
+	}
+	return s, f, nil
+}
+
+type rawStream struct {
+	r   io.Reader
+	raw Raw
+	buf []byte
+	img *image.RGBA
+}
+
+// NewRaw returns a stream of raw RGB frames.
+func NewRaw(r io.Reader, raw Raw) Stream {
+	return &rawStream{
+		r:   r,
+		raw: raw,
+		buf: make([]byte, raw.W*raw.H*3),
+		img: image.NewRGBA(image.Rect(0, 0, raw.W, raw.H)),
+	}
+}
+
+func (s *rawStream) Next() (*image.RGBA, error) {
+	if _, err := io.ReadFull(s.r, s.buf); err == io.ErrUnexpectedEOF {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, err
+	}
+	for i := 0; i < len(s.buf)/3; i++ {
+		copy(s.img.Pix[i*4:], s.buf[i*3:i*3+3])
+		s.img.Pix[i*4+3] = 255
+	}
+	return s.img, nil
+}
+
+func (s *rawStream) FrameRate() float64 { return s.raw.FPS }
+func (s *rawStream) Size() (int, int)   { return s.raw.W, s.raw.H }
@@ -0,0 +1,162 @@
+package video
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"strconv"
+	"strings"
+)
+
+type y4mStream struct {
+	r      *bufio.Reader
+	w, h   int
+	fps    float64
+	full   bool
+	planes [3][]byte
+	// chroma subsampling in x and y
+	cx, cy int
+	mono   bool
+	alpha  []byte
+	img    *image.RGBA
+}
+
+// NewY4M returns a stream of a YUV4MPEG2 file. It supports 8 bit 4:2:0,
+// 4:1:1, 4:2:2, 4:4:4 and mono chroma formats.
+func NewY4M(r *bufio.Reader) (Stream, error) {
+	header, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) == 0 || fields[0] != "YUV4MPEG2" {
+		return nil, errors.New("missing YUV4MPEG2 header")
+	}
+	s := &y4mStream{r: r, fps: 25, cx: 2, cy: 2}
+	for _, f := range fields[1:] {
+		v := f[1:]
+		switch f[0] {
+		case 'W':
+			s.w, err = strconv.Atoi(v)
+		case 'H':
+			s.h, err = strconv.Atoi(v)
+		case 'F':
+			var num, den float64
+			if _, err = fmt.Sscanf(v, "%g:%g", &num, &den); err == nil && num > 0 && den > 0 {
+				s.fps = num / den
+			}
+		case 'C':
+			err = s.setChroma(v)
+		case 'X':
+			if v == "COLORRANGE=FULL" {
+				s.full = true
+			}
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid header field %q: %v", f, err)
+		}
+	}
+	if s.w <= 0 || s.h <= 0 {
+		return nil, errors.New("missing frame size")
+	}
+	cw, ch := (s.w+s.cx-1)/s.cx, (s.h+s.cy-1)/s.cy
+	s.planes[0] = make([]byte, s.w*s.h)
+	if !s.mono {
+		s.planes[1] = make([]byte, cw*ch)
+		s.planes[2] = make([]byte, cw*ch)
+	}
+	if s.alpha != nil {
+		s.alpha = make([]byte, s.w*s.h)
+	}
+	s.img = image.NewRGBA(image.Rect(0, 0, s.w, s.h))
+	return s, nil
+}
+
+func (s *y4mStream) setChroma(c string) error {
+	switch {
+	case strings.HasPrefix(c, "420"):
+		s.cx, s.cy = 2, 2
+	case c == "411":
+		s.cx, s.cy = 4, 1
+	case c == "422":
+		s.cx, s.cy = 2, 1
+	case c == "444":
+		s.cx, s.cy = 1, 1
+	case c == "444alpha":
+		s.cx, s.cy = 1, 1
+		s.alpha = []byte{}
+	case c == "mono":
+		s.mono = true
+	default:
+		return fmt.Errorf("unsupported chroma format %q", c)
+	}
+	if strings.HasPrefix(c, "420p") && c != "420paldv" {
+		// 420p10, 420p12, ...
+		return fmt.Errorf("unsupported bit depth %q", c)
+	}
+	return nil
+}
+
+func (s *y4mStream) Next() (*image.RGBA, error) {
+	line, err := s.r.ReadString('\n')
+	if err == io.EOF && line == "" {
+		return nil, io.EOF
+	} else if err != nil {
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "FRAME") {
+		return nil, fmt.Errorf("invalid frame header %q", strings.TrimSpace(line))
+	}
+	for _, p := range append(s.planes[:], s.alpha) {
+		if _, err := io.ReadFull(s.r, p); err == io.ErrUnexpectedEOF {
+			return nil, io.EOF
+		} else if err != nil {
+			return nil, err
+		}
+	}
+
+	cw := (s.w + s.cx - 1) / s.cx
+	for y := 0; y < s.h; y++ {
+		for x := 0; x < s.w; x++ {
+			yy := s.planes[0][y*s.w+x]
+			cb, cr := uint8(128), uint8(128)
+			if !s.mono {
+				i := y/s.cy*cw + x/s.cx
+				cb, cr = s.planes[1][i], s.planes[2][i]
+			}
+			var r, g, b uint8
+			if s.full {
+				r, g, b = color.YCbCrToRGB(yy, cb, cr)
+			} else {
+				r, g, b = limitedToRGB(yy, cb, cr)
+			}
+			o := (y*s.w + x) * 4
+			s.img.Pix[o], s.img.Pix[o+1], s.img.Pix[o+2], s.img.Pix[o+3] = r, g, b, 255
+		}
+	}
+	return s.img, nil
+}
+
+// limitedToRGB converts BT.601 YCbCr with the video range of 16-235 to
+// RGB.
+func limitedToRGB(y, cb, cr uint8) (uint8, uint8, uint8) {
+	yf := 1.164 * (float64(y) - 16)
+	cbf, crf := float64(cb)-128, float64(cr)-128
+	return clamp(yf + 1.596*crf), clamp(yf - 0.392*cbf - 0.813*crf), clamp(yf + 2.017*cbf)
+}
+
+func clamp(v float64) uint8 {
+	if v <= 0 {
+		return 0
+	}
+	if v >= 255 {
+		return 255
+	}
+	return uint8(v + 0.5)
+}
+
+func (s *y4mStream) FrameRate() float64 { return s.fps }
+func (s *y4mStream) Size() (int, int)   { return s.w, s.h }
@@ -0,0 +1,52 @@
+package insta
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+)
+
+// View is a rectangular region of an image with its own coordinate system,
+// the top left pixel of the region is at 0/0. Drawing is clipped to the
+// region.
+type View struct {
+	dst draw.Image
+	r   image.Rectangle
+}
+
+// NewView returns a view of the region r of dst.
+func NewView(dst draw.Image, r image.Rectangle) *View {
+	return &View{dst: dst, r: r.Intersect(dst.Bounds())}
+}
+
+// View returns a view of the region r of the screen.
+func (s *Screen) View(r image.Rectangle) *View {
+	return NewView(s, r)
+}
+
+func (v *View) Set(x, y int, c color.Color) {
+	if x < 0 || y < 0 || x >= v.r.Dx() || y >= v.r.Dy() {
+		return
+	}
+	v.dst.Set(x+v.r.Min.X, y+v.r.Min.Y, c)
+}
+
+func (v *View) At(x, y int) color.Color {
+	if x < 0 || y < 0 || x >= v.r.Dx() || y >= v.r.Dy() {
+		return color.RGBA{}
+	}
+	return v.dst.At(x+v.r.Min.X, y+v.r.Min.Y)
+}
+
+func (v *View) Bounds() image.Rectangle {
+	return image.Rect(0, 0, v.r.Dx(), v.r.Dy())
+}
+
+func (v *View) ColorModel() color.Model {
+	return v.dst.ColorModel()
+}
+
+// Region returns the region of the view in the coordinates of the parent.
+func (v *View) Region() image.Rectangle {
+	return v.r
+}